@@ -0,0 +1,95 @@
+package amount
+
+import (
+	"fmt"
+	"math/big"
+	"sort"
+)
+
+// Allocate splits `total` into parts proportional to `weights`.
+// Parts sum exactly to the total, the remainder is spread deterministically (see AllocateUnit)
+func Allocate(total *Amount, weights ...*big.Int) ([]*Amount, error) {
+	parts, _, err := AllocateUnit(total, nil, weights...)
+	return parts, err
+}
+
+// AllocateUnit splits `total` into parts proportional to `weights`, every part is a multiple of `unit` (nil unit is the smallest possible amount).
+// The remainder is spread unit by unit to the parts with the largest fractional share, ties are broken by lower index.
+// Returned `dust` is a part of the total that can't be represented in units (total mod unit), so parts and dust sum exactly to the total
+func AllocateUnit(total *Amount, unit *Amount, weights ...*big.Int) (parts []*Amount, dust *Amount, err error) {
+	if total == nil || total.Value == nil || total.Value.Sign() < 0 {
+		return nil, nil, fmt.Errorf("total amount is nil or negative")
+	}
+	u := big.NewInt(1)
+	if unit != nil {
+		if unit.Value == nil || unit.Value.Sign() <= 0 {
+			return nil, nil, fmt.Errorf("unit amount is nil or not positive")
+		}
+		u.Set(unit.Value)
+	}
+	if len(weights) == 0 {
+		return nil, nil, fmt.Errorf("weights are empty")
+	}
+
+	// sum of weights
+	sum := big.NewInt(0)
+	for i, w := range weights {
+		if w == nil || w.Sign() < 0 {
+			return nil, nil, fmt.Errorf("weight at index %v is nil or negative", i)
+		}
+		sum.Add(sum, w)
+	}
+	if sum.Sign() == 0 {
+		return nil, nil, fmt.Errorf("weights sum is zero")
+	}
+
+	// total in units and dust
+	units, rem := new(big.Int).QuoRem(total.Value, u, new(big.Int))
+
+	// integer shares and fractional remainders (largest remainder method)
+	type share struct {
+		index int
+		rem   *big.Int
+	}
+	quots := make([]*big.Int, len(weights))
+	shares := make([]share, len(weights))
+	left := new(big.Int).Set(units)
+	for i, w := range weights {
+		q, r := new(big.Int).QuoRem(new(big.Int).Mul(units, w), sum, new(big.Int))
+		quots[i] = q
+		shares[i] = share{i, r}
+		left.Sub(left, q)
+	}
+
+	// spread the remainder (less than count of weights)
+	sort.SliceStable(shares, func(i, j int) bool {
+		return shares[i].rem.Cmp(shares[j].rem) > 0
+	})
+	for i := int64(0); i < left.Int64(); i++ {
+		quots[shares[i].index].Add(quots[shares[i].index], big.NewInt(1))
+	}
+
+	parts = make([]*Amount, len(weights))
+	for i, q := range quots {
+		parts[i] = FromBig(q.Mul(q, u))
+	}
+	return parts, FromBig(rem), nil
+}
+
+// SplitEven splits `total` into `n` equal parts which sum exactly to the total, the first parts get the remainder
+func SplitEven(total *Amount, n int) ([]*Amount, error) {
+	parts, _, err := SplitEvenUnit(total, nil, n)
+	return parts, err
+}
+
+// SplitEvenUnit does the same as SplitEven, but every part is a multiple of `unit`. See AllocateUnit
+func SplitEvenUnit(total *Amount, unit *Amount, n int) (parts []*Amount, dust *Amount, err error) {
+	if n <= 0 {
+		return nil, nil, fmt.Errorf("parts count must be positive, got %v", n)
+	}
+	weights := make([]*big.Int, n)
+	for i := range weights {
+		weights[i] = big.NewInt(1)
+	}
+	return AllocateUnit(total, unit, weights...)
+}
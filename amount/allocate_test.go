@@ -0,0 +1,116 @@
+package amount
+
+import (
+	"math/big"
+	"math/rand"
+	"testing"
+)
+
+func TestAllocate(t *testing.T) {
+	w := func(v ...int64) []*big.Int {
+		ret := make([]*big.Int, len(v))
+		for i, x := range v {
+			ret[i] = big.NewInt(x)
+		}
+		return ret
+	}
+	tests := []struct {
+		name    string
+		total   string
+		unit    string
+		weights []*big.Int
+		want    []string
+		dust    string
+	}{
+		{"equal", "0.000000000000000010", "", w(1, 1, 1), []string{"0.000000000000000004", "0.000000000000000003", "0.000000000000000003"}, "0.000000000000000000"},
+		{"largest remainder", "0.000000000000000010", "", w(1, 2, 3), []string{"0.000000000000000002", "0.000000000000000003", "0.000000000000000005"}, "0.000000000000000000"},
+		{"zero weight", "1", "", w(0, 1, 1), []string{"0.000000000000000000", "0.500000000000000000", "0.500000000000000000"}, "0.000000000000000000"},
+		{"unit", "1.0000015", "0.000001", w(1, 1), []string{"0.500001000000000000", "0.500000000000000000"}, "0.000000500000000000"},
+		{"zero total", "0", "", w(5, 7), []string{"0.000000000000000000", "0.000000000000000000"}, "0.000000000000000000"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var unit *Amount
+			if tt.unit != "" {
+				unit = MustFromString(tt.unit)
+			}
+			parts, dust, err := AllocateUnit(MustFromString(tt.total), unit, tt.weights...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(parts) != len(tt.want) {
+				t.Fatalf("AllocateUnit() got %v parts, want %v", len(parts), len(tt.want))
+			}
+			for i, p := range parts {
+				if p.String() != tt.want[i] {
+					t.Errorf("AllocateUnit() part %v = %v, want %v", i, p, tt.want[i])
+				}
+			}
+			if dust.String() != tt.dust {
+				t.Errorf("AllocateUnit() dust = %v, want %v", dust, tt.dust)
+			}
+		})
+	}
+}
+
+func TestAllocate_Sum(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+	for i := 0; i < 1000; i++ {
+		total := FromBig(new(big.Int).Rand(rnd, FromInteger(1000000).Value))
+		unit := FromBig(big.NewInt(rnd.Int63n(1000000) + 1))
+		weights := make([]*big.Int, rnd.Intn(20)+1)
+		for j := range weights {
+			weights[j] = big.NewInt(rnd.Int63n(1000))
+		}
+		weights[0].Add(weights[0], big.NewInt(1))
+
+		parts, dust, err := AllocateUnit(total, unit, weights...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		sum := new(big.Int).Set(dust.Value)
+		for _, p := range parts {
+			if new(big.Int).Mod(p.Value, unit.Value).Sign() != 0 {
+				t.Fatalf("AllocateUnit() part %v is not a multiple of %v", p, unit)
+			}
+			sum.Add(sum, p.Value)
+		}
+		if sum.Cmp(total.Value) != 0 {
+			t.Fatalf("AllocateUnit() sum = %v, want %v", FromBig(sum), total)
+		}
+		if dust.Value.Cmp(unit.Value) >= 0 {
+			t.Fatalf("AllocateUnit() dust %v is not less than unit %v", dust, unit)
+		}
+	}
+}
+
+func TestAllocate_Errors(t *testing.T) {
+	if _, err := Allocate(MustFromString("-1"), big.NewInt(1)); err == nil {
+		t.Error("Allocate() should fail on negative total")
+	}
+	if _, err := Allocate(MustFromString("1")); err == nil {
+		t.Error("Allocate() should fail on empty weights")
+	}
+	if _, err := Allocate(MustFromString("1"), big.NewInt(0), big.NewInt(0)); err == nil {
+		t.Error("Allocate() should fail on zero weights")
+	}
+	if _, err := Allocate(MustFromString("1"), big.NewInt(-1), big.NewInt(2)); err == nil {
+		t.Error("Allocate() should fail on negative weight")
+	}
+	if _, err := SplitEven(MustFromString("1"), 0); err == nil {
+		t.Error("SplitEven() should fail on zero parts")
+	}
+}
+
+func TestSplitEven(t *testing.T) {
+	parts, err := SplitEven(MustFromString("0.000000000000000005"), 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"0.000000000000000002", "0.000000000000000002", "0.000000000000000001"}
+	for i, p := range parts {
+		if p.String() != want[i] {
+			t.Errorf("SplitEven() part %v = %v, want %v", i, p, want[i])
+		}
+	}
+}
@@ -0,0 +1,327 @@
+package archive
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"sync"
+
+	mint "github.com/void616/gm.mint"
+	"github.com/void616/gm.mint/block"
+	"github.com/void616/gm.mint/transaction"
+)
+
+// Record of the archive file: data length (uint32 LE), CRC-32C of the data (uint32 LE), raw block data
+const recordHeaderSize = 8
+
+var crcTable = crc32.MakeTable(crc32.Castagnoli)
+
+var (
+	// ErrNotFound means the archive has no such block or transaction
+	ErrNotFound = errors.New("not found")
+	// ErrCorrupt means the archive file is damaged before its tail and can't be repaired
+	ErrCorrupt = errors.New("archive is corrupt")
+)
+
+// TxLocation is a place of a transaction in the archive
+type TxLocation struct {
+	// BlockID of the block
+	BlockID uint64
+	// Index of the transaction within the block
+	Index int
+}
+
+// Store is an append-only file of raw blocks with consecutive IDs. Records are checksummed,
+// indexes by block ID, header digest and transaction digest are kept in memory and rebuilt on open.
+// It's safe for concurrent use
+type Store struct {
+	mu       sync.RWMutex
+	f        *os.File
+	size     int64
+	repaired int64
+	first    uint64
+	offsets  []int64
+	digests  map[mint.Digest]uint64
+	txs      map[transaction.TxID]TxLocation
+}
+
+// Open the archive file, it's created if missing. A torn tail (an incomplete or damaged last record) left by a crash
+// is truncated, see Repaired
+func Open(path string) (*Store, error) {
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return nil, err
+	}
+	s := &Store{
+		f:       f,
+		digests: make(map[mint.Digest]uint64),
+		txs:     make(map[transaction.TxID]TxLocation),
+	}
+	if err := s.load(); err != nil {
+		f.Close()
+		return nil, err
+	}
+	return s, nil
+}
+
+// Close the file
+func (s *Store) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.f.Close()
+}
+
+// Repaired is a number of bytes truncated on open
+func (s *Store) Repaired() int64 {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.repaired
+}
+
+// Len is a number of blocks in the archive
+func (s *Store) Len() int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return len(s.offsets)
+}
+
+// First block ID in the archive, false if it's empty
+func (s *Store) First() (uint64, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.first, len(s.offsets) > 0
+}
+
+// Last block ID in the archive, false if it's empty
+func (s *Store) Last() (uint64, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if len(s.offsets) == 0 {
+		return 0, false
+	}
+	return s.first + uint64(len(s.offsets)) - 1, true
+}
+
+// Append the block data. The block must parse and follow the last block ID (any ID for an empty archive).
+// The data is synced to the disk before return
+func (s *Store) Append(data []byte) (*block.Header, error) {
+	h, txs, err := scan(data)
+	if err != nil {
+		return nil, err
+	}
+	if !h.BlockID.IsUint64() {
+		return nil, fmt.Errorf("block ID %v is out of range", h.BlockID)
+	}
+	id := h.BlockID.Uint64()
+	if uint64(len(data)) > 0xFFFFFFFF {
+		return nil, fmt.Errorf("block %v is too large", id)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if len(s.offsets) > 0 {
+		if next := s.first + uint64(len(s.offsets)); id != next {
+			return nil, fmt.Errorf("block %v doesn't follow the last block %v", id, next-1)
+		}
+	}
+	if prev, ok := s.digests[h.Digest]; ok {
+		return nil, fmt.Errorf("block %v has the same digest as block %v", id, prev)
+	}
+
+	rec := make([]byte, recordHeaderSize+len(data))
+	binary.LittleEndian.PutUint32(rec[0:], uint32(len(data)))
+	binary.LittleEndian.PutUint32(rec[4:], crc32.Checksum(data, crcTable))
+	copy(rec[recordHeaderSize:], data)
+	if _, err := s.f.WriteAt(rec, s.size); err != nil {
+		// drop a partial write, it would be a torn tail otherwise
+		if terr := s.f.Truncate(s.size); terr != nil {
+			return nil, fmt.Errorf("%v; truncate: %v", err, terr)
+		}
+		return nil, err
+	}
+	if err := s.f.Sync(); err != nil {
+		return nil, err
+	}
+	s.index(id, s.size, h, txs)
+	s.size += int64(len(rec))
+	return h, nil
+}
+
+// Block data by ID
+func (s *Store) Block(id uint64) ([]byte, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.read(id)
+}
+
+// BlockByDigest gets block data by the header digest
+func (s *Store) BlockByDigest(d mint.Digest) (uint64, []byte, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	id, ok := s.digests[d]
+	if !ok {
+		return 0, nil, ErrNotFound
+	}
+	b, err := s.read(id)
+	return id, b, err
+}
+
+// FindTx locates the transaction by its digest
+func (s *Store) FindTx(id transaction.TxID) (TxLocation, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	loc, ok := s.txs[id]
+	return loc, ok
+}
+
+// Range calls `cbk` with data of blocks from `from` to `to` inclusive (clamped to the archive), in order.
+// Iteration stops on the first error returned by `cbk`
+func (s *Store) Range(from, to uint64, cbk func(id uint64, data []byte) error) error {
+	s.mu.RLock()
+	n := uint64(len(s.offsets))
+	first, last := s.first, s.first+n-1
+	s.mu.RUnlock()
+	if n == 0 {
+		return nil
+	}
+	if from < first {
+		from = first
+	}
+	if to > last {
+		to = last
+	}
+	for id := from; id <= to; id++ {
+		b, err := s.Block(id)
+		if err != nil {
+			return err
+		}
+		if err := cbk(id, b); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Parse blocks from `from` to `to` inclusive with block.ParseBytes (see Range)
+func (s *Store) Parse(from, to uint64, cbkHeader block.CbkHeader, cbkTransaction block.CbkTransaction) error {
+	return s.Range(from, to, func(id uint64, data []byte) error {
+		if err := block.ParseBytes(data, cbkHeader, cbkTransaction); err != nil {
+			return fmt.Errorf("block %v: %w", id, err)
+		}
+		return nil
+	})
+}
+
+// ---
+
+// load scans the file, rebuilds the indexes and truncates a torn tail
+func (s *Store) load() error {
+	fi, err := s.f.Stat()
+	if err != nil {
+		return err
+	}
+	size := fi.Size()
+	r := io.NewSectionReader(s.f, 0, size)
+	hdr := make([]byte, recordHeaderSize)
+
+	var off int64
+	for off < size {
+		torn := func() error {
+			if err := s.f.Truncate(off); err != nil {
+				return err
+			}
+			s.size, s.repaired = off, size-off
+			return s.f.Sync()
+		}
+		if size-off < recordHeaderSize {
+			return torn()
+		}
+		if _, err := r.ReadAt(hdr, off); err != nil {
+			return err
+		}
+		n := int64(binary.LittleEndian.Uint32(hdr[0:]))
+		end := off + recordHeaderSize + n
+		if end > size {
+			return torn()
+		}
+		data := make([]byte, n)
+		if _, err := r.ReadAt(data, off+recordHeaderSize); err != nil {
+			return err
+		}
+		if crc32.Checksum(data, crcTable) != binary.LittleEndian.Uint32(hdr[4:]) {
+			if end == size {
+				return torn()
+			}
+			return fmt.Errorf("%w: checksum mismatch at offset %v", ErrCorrupt, off)
+		}
+
+		h, txs, err := scan(data)
+		if err != nil {
+			return fmt.Errorf("%w: block at offset %v: %v", ErrCorrupt, off, err)
+		}
+		if !h.BlockID.IsUint64() {
+			return fmt.Errorf("%w: block ID %v at offset %v is out of range", ErrCorrupt, h.BlockID, off)
+		}
+		id := h.BlockID.Uint64()
+		if len(s.offsets) > 0 && id != s.first+uint64(len(s.offsets)) {
+			return fmt.Errorf("%w: block %v at offset %v doesn't follow block %v", ErrCorrupt, id, off, s.first+uint64(len(s.offsets))-1)
+		}
+		s.index(id, off, h, txs)
+		off = end
+	}
+	s.size = off
+	return nil
+}
+
+func (s *Store) index(id uint64, off int64, h *block.Header, txs []transaction.TxID) {
+	if len(s.offsets) == 0 {
+		s.first = id
+	}
+	s.offsets = append(s.offsets, off)
+	s.digests[h.Digest] = id
+	for i, tx := range txs {
+		s.txs[tx] = TxLocation{BlockID: id, Index: i}
+	}
+}
+
+func (s *Store) read(id uint64) ([]byte, error) {
+	if id < s.first || id-s.first >= uint64(len(s.offsets)) {
+		return nil, ErrNotFound
+	}
+	off := s.offsets[id-s.first]
+	hdr := make([]byte, recordHeaderSize)
+	if _, err := s.f.ReadAt(hdr, off); err != nil {
+		return nil, err
+	}
+	data := make([]byte, binary.LittleEndian.Uint32(hdr[0:]))
+	if _, err := s.f.ReadAt(data, off+recordHeaderSize); err != nil {
+		return nil, err
+	}
+	if crc32.Checksum(data, crcTable) != binary.LittleEndian.Uint32(hdr[4:]) {
+		return nil, fmt.Errorf("%w: checksum mismatch of block %v", ErrCorrupt, id)
+	}
+	return data, nil
+}
+
+// scan parses the block for its header and transaction digests
+func scan(data []byte) (*block.Header, []transaction.TxID, error) {
+	var (
+		header *block.Header
+		txs    []transaction.TxID
+	)
+	err := block.ParseBytes(data, func(h *block.Header) error {
+		header = h
+		return nil
+	}, block.Envelopes(func(_ *transaction.Envelope, _ transaction.Transactioner, ptx *transaction.ParsedTransaction, _ *block.Header) error {
+		txs = append(txs, ptx.ID())
+		return nil
+	}))
+	if err != nil {
+		return nil, nil, err
+	}
+	return header, txs, nil
+}
@@ -0,0 +1,200 @@
+package archive
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"io/ioutil"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+
+	mint "github.com/void616/gm.mint"
+	"github.com/void616/gm.mint/amount"
+	"github.com/void616/gm.mint/block"
+	"github.com/void616/gm.mint/devnet"
+	"github.com/void616/gm.mint/fee"
+	"github.com/void616/gm.mint/signer"
+	"github.com/void616/gm.mint/transaction"
+)
+
+// chain makes blocks 0..n-1, every non-genesis block has a transaction
+func chain(t *testing.T, n int) ([][]byte, []transaction.TxID) {
+	t.Helper()
+	s, _ := signer.New()
+	c, err := devnet.New(devnet.Config{Genesis: devnet.Genesis{Accounts: map[mint.PublicKey]devnet.Allocation{
+		s.PublicKey(): {Balances: fee.Balances{mint.TokenMNT: amount.MustFromString("100")}},
+	}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var ids []transaction.TxID
+	for i := 1; i < n; i++ {
+		e, tx, err := transaction.Seal(&transaction.UserData{Data: []byte{byte(i)}}, s, uint64(i))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := c.Submit(context.Background(), e); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := c.Seal(); err != nil {
+			t.Fatal(err)
+		}
+		ids = append(ids, tx.ID())
+	}
+	var blocks [][]byte
+	for i := 0; i < n; i++ {
+		b, _ := c.Block(uint64(i))
+		blocks = append(blocks, b)
+	}
+	return blocks, ids
+}
+
+func tempFile(t *testing.T) (string, func()) {
+	dir, err := ioutil.TempDir("", "archive")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return filepath.Join(dir, "blocks.dat"), func() { os.RemoveAll(dir) }
+}
+
+func TestStore(t *testing.T) {
+	path, cleanup := tempFile(t)
+	defer cleanup()
+	blocks, ids := chain(t, 4)
+
+	s, err := Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := s.Last(); ok {
+		t.Fatal("empty archive has the last block")
+	}
+	for _, b := range blocks {
+		if _, err := s.Append(b); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := s.Append(blocks[2]); err == nil {
+		t.Fatal("block 2 is appended after block 3")
+	}
+	s.Close()
+
+	// indexes are rebuilt
+	s, err = Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	if first, _ := s.First(); first != 0 || s.Len() != 4 || s.Repaired() != 0 {
+		t.Fatalf("first %v, len %v, repaired %v", first, s.Len(), s.Repaired())
+	}
+	b, err := s.Block(2)
+	if err != nil || string(b) != string(blocks[2]) {
+		t.Fatalf("block 2: %v", err)
+	}
+	if _, err := s.Block(4); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("block 4: %v", err)
+	}
+	if loc, ok := s.FindTx(ids[2]); !ok || loc.BlockID != 3 || loc.Index != 0 {
+		t.Fatalf("tx location %+v", loc)
+	}
+	var digests []mint.Digest
+	var txs int
+	err = s.Parse(1, 100, func(h *block.Header) error {
+		digests = append(digests, h.Digest)
+		return nil
+	}, block.Envelopes(func(*transaction.Envelope, transaction.Transactioner, *transaction.ParsedTransaction, *block.Header) error {
+		txs++
+		return nil
+	}))
+	if err != nil || len(digests) != 3 || txs != 3 {
+		t.Fatalf("parsed %v blocks, %v transactions: %v", len(digests), txs, err)
+	}
+	if id, b, err := s.BlockByDigest(digests[0]); err != nil || id != 1 || string(b) != string(blocks[1]) {
+		t.Fatalf("block by digest: %v, %v", id, err)
+	}
+
+	// range is clamped to the archive, stops on error
+	var got []uint64
+	collect := func(id uint64, _ []byte) error {
+		got = append(got, id)
+		if id == 2 {
+			return ErrNotFound
+		}
+		return nil
+	}
+	if err := s.Range(0, math.MaxUint64, collect); !errors.Is(err, ErrNotFound) || len(got) != 3 {
+		t.Fatalf("Range() = %v, %v", got, err)
+	}
+	got = nil
+	if err := s.Range(3, 1, collect); err != nil || len(got) != 0 {
+		t.Fatalf("Range() = %v, %v", got, err)
+	}
+	if err := s.Range(3, 3, collect); err != nil || len(got) != 1 {
+		t.Fatalf("Range() = %v, %v", got, err)
+	}
+}
+
+func TestStore_TornTail(t *testing.T) {
+	path, cleanup := tempFile(t)
+	defer cleanup()
+	blocks, _ := chain(t, 4)
+
+	s, err := Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, b := range blocks[:3] {
+		if _, err := s.Append(b); err != nil {
+			t.Fatal(err)
+		}
+	}
+	s.Close()
+	fi, _ := os.Stat(path)
+	good := fi.Size()
+
+	for name, tail := range map[string]func(f *os.File){
+		"partial header": func(f *os.File) { f.Write([]byte{1, 2, 3}) },
+		"partial data": func(f *os.File) {
+			f.Write([]byte{0xff, 0, 0, 0, 0, 0, 0, 0})
+			f.Write(blocks[3][:10])
+		},
+		"bad checksum": func(f *os.File) {
+			hdr := make([]byte, recordHeaderSize)
+			binary.LittleEndian.PutUint32(hdr, uint32(len(blocks[3])))
+			f.Write(hdr)
+			f.Write(blocks[3])
+		},
+	} {
+		f, _ := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0644)
+		tail(f)
+		f.Close()
+
+		s, err := Open(path)
+		if err != nil {
+			t.Fatalf("%v: %v", name, err)
+		}
+		fi, _ := os.Stat(path)
+		if s.Len() != 3 || s.Repaired() == 0 || fi.Size() != good {
+			t.Fatalf("%v: len %v, repaired %v, size %v", name, s.Len(), s.Repaired(), fi.Size())
+		}
+		s.Close()
+	}
+
+	// appending continues after the repair
+	s, _ = Open(path)
+	if _, err := s.Append(blocks[3]); err != nil {
+		t.Fatal(err)
+	}
+	s.Close()
+
+	// damage before the tail is not repaired
+	f, _ := os.OpenFile(path, os.O_WRONLY, 0644)
+	f.WriteAt([]byte{0xff}, recordHeaderSize+1)
+	f.Close()
+	if _, err := Open(path); !errors.Is(err, ErrCorrupt) {
+		t.Fatalf("corrupt archive: %v", err)
+	}
+}
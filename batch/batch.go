@@ -0,0 +1,301 @@
+package batch
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"runtime"
+	"strings"
+	"sync"
+
+	mint "github.com/void616/gm.mint"
+	"github.com/void616/gm.mint/amount"
+	"github.com/void616/gm.mint/fee"
+	"github.com/void616/gm.mint/nonce"
+	"github.com/void616/gm.mint/signer"
+	"github.com/void616/gm.mint/transaction"
+)
+
+// ErrStalled means the row is signed, but its transaction would never be included into the chain:
+// a row with a lower nonce failed at signing
+var ErrStalled = errors.New("stalled by a failed row with a lower nonce")
+
+// Payment is a row of a batch
+type Payment struct {
+	Address mint.PublicKey
+	Token   mint.Token
+	Amount  *amount.Amount
+}
+
+// Builder signs batches of TransferAsset transactions
+type Builder struct {
+	// Signer of the transactions
+	Signer *signer.Signer
+	// Nonce of the first transaction (non-zero, the first nonce of an address is 1), the following ones are consecutive.
+	// Ignored if Nonces is set
+	Nonce uint64
+	// Nonces allocates the nonces of the signer, optional
+	Nonces *nonce.Manager
+	// Schedule of the fee, the default one if nil
+	Schedule *fee.Schedule
+	// Balances of the signer to estimate the fee (MNT balance is required for GOLD transfers)
+	Balances fee.Balances
+	// Workers signing in parallel, runtime.NumCPU() if zero
+	Workers int
+}
+
+// Result of a row
+type Result struct {
+	// Index of the row (zero-based, the header of CSV is not counted)
+	Index int
+	// Payment of the row, zero if the CSV row is malformed
+	Payment Payment
+	// Nonce assigned
+	Nonce uint64
+	// Fee of the transaction
+	Fee *fee.Estimation
+	// Signed transaction
+	Signed *transaction.SignedTransaction
+	// Err of the row, other fields except Index (and possibly Payment) are empty
+	Err error
+}
+
+// Batch of the results in order of the rows
+type Batch struct {
+	Results []Result
+	// Principal is a total amount per token of the signed transactions
+	Principal map[mint.Token]*amount.Amount
+	// Fee is a total fee per token of the signed transactions
+	Fee map[mint.Token]*amount.Amount
+	// Failed is a count of rows with errors
+	Failed int
+}
+
+// ParseCSV parses rows "address,token,amount" with an optional header row (starting with "address").
+// Malformed rows are returned as errors at the same index, so the rows could be passed to BuildRows
+func ParseCSV(r io.Reader) ([]Payment, []error, error) {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+	cr.TrimLeadingSpace = true
+	records, err := cr.ReadAll()
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(records) > 0 && len(records[0]) > 0 && strings.EqualFold(records[0][0], "address") {
+		records = records[1:]
+	}
+
+	payments := make([]Payment, len(records))
+	errs := make([]error, len(records))
+	for i, rec := range records {
+		payments[i], errs[i] = parseRecord(rec)
+	}
+	return payments, errs, nil
+}
+
+func parseRecord(rec []string) (Payment, error) {
+	p := Payment{}
+	if len(rec) != 3 {
+		return p, fmt.Errorf("row has %v fields, want 3", len(rec))
+	}
+	addr, err := mint.ParsePublicKey(strings.TrimSpace(rec[0]))
+	if err != nil {
+		return p, fmt.Errorf("address: %v", err)
+	}
+	token, err := mint.ParseToken(strings.TrimSpace(rec[1]))
+	if err != nil {
+		return p, fmt.Errorf("token: %v", err)
+	}
+	amt, err := amount.FromString(strings.TrimSpace(rec[2]))
+	if err != nil {
+		return p, fmt.Errorf("amount: %v", err)
+	}
+	return Payment{Address: addr, Token: token, Amount: amt}, nil
+}
+
+// BuildCSV parses CSV rows (see ParseCSV) and builds the batch
+func (b *Builder) BuildCSV(r io.Reader) (*Batch, error) {
+	payments, errs, err := ParseCSV(r)
+	if err != nil {
+		return nil, err
+	}
+	return b.BuildRows(payments, errs)
+}
+
+// Build the batch of the payments
+func (b *Builder) Build(payments []Payment) (*Batch, error) {
+	return b.BuildRows(payments, nil)
+}
+
+// BuildRows builds the batch, rows with non-nil `errs[i]` are failed as is.
+// Valid rows get consecutive nonces and are signed in parallel, invalid rows don't consume nonces.
+// A row failed at signing (unlikely after validation) leaves its nonce unused, so the following rows fail with ErrStalled
+// instead of leaving a gap. Nonces of all these rows are released to Nonces, if set; a failed release is reported in the row error
+func (b *Builder) BuildRows(payments []Payment, errs []error) (*Batch, error) {
+	if b.Signer == nil {
+		return nil, fmt.Errorf("signer is nil")
+	}
+	if b.Nonces == nil && b.Nonce == 0 {
+		return nil, fmt.Errorf("nonce is zero")
+	}
+	if errs != nil && len(errs) != len(payments) {
+		return nil, fmt.Errorf("errors count %v mismatches payments count %v", len(errs), len(payments))
+	}
+	schedule := b.Schedule
+	if schedule == nil {
+		schedule = fee.Default()
+	}
+	from := b.Signer.PublicKey()
+
+	// validate and estimate
+	results := make([]Result, len(payments))
+	txs := make([]*transaction.TransferAsset, len(payments))
+	for i, p := range payments {
+		results[i] = Result{Index: i, Payment: p}
+		if errs != nil && errs[i] != nil {
+			results[i].Err = errs[i]
+			continue
+		}
+		tx := &transaction.TransferAsset{Token: p.Token, Address: p.Address, Amount: p.Amount}
+		if err := transaction.ValidateSender(tx, from); err != nil {
+			results[i].Err = err
+			continue
+		}
+		// encodable (like an amount out of range)
+		if _, err := transaction.Unsigned(tx, from, 0); err != nil {
+			results[i].Err = err
+			continue
+		}
+		est, err := schedule.Estimate(tx, b.Balances)
+		if err != nil {
+			results[i].Err = err
+			continue
+		}
+		results[i].Fee = est
+		txs[i] = tx
+	}
+
+	// nonces
+	next := b.Nonce
+	for i, tx := range txs {
+		if tx == nil {
+			continue
+		}
+		if b.Nonces == nil {
+			results[i].Nonce = next
+			next++
+			continue
+		}
+		n, err := b.Nonces.Next(from)
+		if err != nil {
+			results[i].Err, results[i].Fee, txs[i] = err, nil, nil
+			continue
+		}
+		results[i].Nonce = n
+	}
+
+	// sign
+	workers := b.Workers
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+	jobs := make(chan int)
+	wg := sync.WaitGroup{}
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				signed, err := txs[i].Sign(b.Signer, results[i].Nonce)
+				if err != nil {
+					results[i].Err, results[i].Fee = err, nil
+					continue
+				}
+				results[i].Signed = signed
+			}
+		}()
+	}
+	for i, tx := range txs {
+		if tx != nil {
+			jobs <- i
+		}
+	}
+	close(jobs)
+	wg.Wait()
+	stall(results, txs)
+	if b.Nonces != nil {
+		release(b.Nonces, from, results, txs)
+	}
+
+	// totals
+	ret := &Batch{
+		Results:   results,
+		Principal: make(map[mint.Token]*amount.Amount),
+		Fee:       make(map[mint.Token]*amount.Amount),
+	}
+	for _, r := range results {
+		if r.Err != nil {
+			ret.Failed++
+			continue
+		}
+		add(ret.Principal, r.Payment.Token, r.Payment.Amount)
+		for t, f := range r.Fee.Fee {
+			add(ret.Fee, t, f)
+		}
+	}
+	return ret, nil
+}
+
+// stall fails signed rows with nonces after the lowest nonce of a row failed at signing
+func stall(results []Result, txs []*transaction.TransferAsset) {
+	var (
+		failed bool
+		lowest uint64
+	)
+	for i, r := range results {
+		if txs[i] != nil && r.Err != nil && (!failed || r.Nonce < lowest) {
+			failed, lowest = true, r.Nonce
+		}
+	}
+	if !failed {
+		return
+	}
+	for i, r := range results {
+		if txs[i] != nil && r.Err == nil && r.Nonce > lowest {
+			results[i].Err = fmt.Errorf("%w: nonce %v", ErrStalled, lowest)
+			results[i].Fee, results[i].Signed = nil, nil
+		}
+	}
+}
+
+// release returns the nonces of failed rows to the manager, a failed release is appended to the row error
+func release(nonces *nonce.Manager, from mint.PublicKey, results []Result, txs []*transaction.TransferAsset) {
+	for i, r := range results {
+		if txs[i] == nil || r.Err == nil {
+			continue
+		}
+		if err := nonces.Release(from, r.Nonce); err != nil {
+			results[i].Err = fmt.Errorf("%w; release nonce %v: %v", r.Err, r.Nonce, err)
+		}
+	}
+}
+
+// Signed returns the signed transactions in order, skipping failed rows
+func (b *Batch) Signed() []*transaction.SignedTransaction {
+	ret := make([]*transaction.SignedTransaction, 0, len(b.Results)-b.Failed)
+	for _, r := range b.Results {
+		if r.Err == nil {
+			ret = append(ret, r.Signed)
+		}
+	}
+	return ret
+}
+
+func add(m map[mint.Token]*amount.Amount, t mint.Token, a *amount.Amount) {
+	if v, ok := m[t]; ok {
+		v.Value.Add(v.Value, a.Value)
+		return
+	}
+	m[t] = amount.FromAmount(a)
+}
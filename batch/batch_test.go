@@ -0,0 +1,180 @@
+package batch
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	mint "github.com/void616/gm.mint"
+	"github.com/void616/gm.mint/amount"
+	"github.com/void616/gm.mint/fee"
+	"github.com/void616/gm.mint/nonce"
+	"github.com/void616/gm.mint/signer"
+	"github.com/void616/gm.mint/transaction"
+)
+
+func TestBuilder_BuildCSV(t *testing.T) {
+	s, _ := signer.New()
+	a, _ := signer.New()
+	c, _ := signer.New()
+
+	doc := strings.Join([]string{
+		"address,token,amount",
+		a.PublicKey().String() + ",GOLD,1.5",
+		"nope,GOLD,1",
+		c.PublicKey().String() + ",mnt,0",
+		s.PublicKey().String() + ",mnt,1",
+		c.PublicKey().String() + ",mnt,2",
+		a.PublicKey().String() + ",gold,1000000000000",
+	}, "\n")
+
+	b := &Builder{
+		Signer:   s,
+		Nonce:    10,
+		Balances: fee.Balances{mint.TokenMNT: amount.MustFromString("10")},
+		Workers:  3,
+	}
+	batch, err := b.BuildCSV(strings.NewReader(doc))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(batch.Results) != 6 || batch.Failed != 4 {
+		t.Fatalf("results %v, failed %v", len(batch.Results), batch.Failed)
+	}
+
+	ok := []struct {
+		index int
+		nonce uint64
+	}{{0, 10}, {4, 11}}
+	for _, o := range ok {
+		r := batch.Results[o.index]
+		if r.Err != nil || r.Index != o.index || r.Nonce != o.nonce || r.Signed == nil {
+			t.Fatalf("row %v: %+v", o.index, r)
+		}
+		tx := &transaction.TransferAsset{}
+		ptx, err := tx.Parse(bytes.NewReader(r.Signed.Data))
+		if err != nil || ptx.Nonce != o.nonce || tx.Address != r.Payment.Address || tx.Amount.Value.Cmp(r.Payment.Amount.Value) != 0 {
+			t.Fatalf("row %v is parsed as %+v, %v", o.index, tx, err)
+		}
+	}
+	if !errors.Is(batch.Results[2].Err, transaction.ErrNotPositive) || !errors.Is(batch.Results[3].Err, transaction.ErrSender) {
+		t.Fatalf("errors: %v, %v", batch.Results[2].Err, batch.Results[3].Err)
+	}
+	if batch.Results[1].Err == nil || batch.Results[5].Err == nil {
+		t.Fatal("malformed rows are not failed")
+	}
+
+	// totals: 1.5 GOLD at 0.03% (10 MNT balance) and 2 MNT at fixed fee
+	want := map[string]string{
+		fmt.Sprint(batch.Principal[mint.TokenGOLD]): "1.500000000000000000",
+		fmt.Sprint(batch.Principal[mint.TokenMNT]):  "2.000000000000000000",
+		fmt.Sprint(batch.Fee[mint.TokenGOLD]):       fee.GoldFee(amount.MustFromString("1.5"), amount.MustFromString("10")).String(),
+		fmt.Sprint(batch.Fee[mint.TokenMNT]):        fee.MntFee(amount.MustFromString("2")).String(),
+	}
+	for got, w := range want {
+		if got != w {
+			t.Fatalf("total %v, want %v", got, w)
+		}
+	}
+	if len(batch.Signed()) != 2 {
+		t.Fatalf("Signed() = %v", len(batch.Signed()))
+	}
+}
+
+func TestBuilder_Nonces(t *testing.T) {
+	s, _ := signer.New()
+	m := nonce.New(nonce.NewMemoryStore())
+	m.Sync(s.PublicKey(), 99)
+
+	payments := make([]Payment, 1000)
+	for i := range payments {
+		payments[i] = Payment{Address: mint.PublicKey{byte(i), 1}, Token: mint.TokenMNT, Amount: amount.FromInteger(int64(i + 1))}
+	}
+	payments[500].Amount = nil
+
+	batch, err := (&Builder{Signer: s, Nonces: m}).Build(payments)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if batch.Failed != 1 || batch.Results[500].Err == nil {
+		t.Fatalf("failed %v", batch.Failed)
+	}
+	for i, r := range batch.Results {
+		want := uint64(100 + i)
+		if i > 500 {
+			want--
+		}
+		if i != 500 && r.Nonce != want {
+			t.Fatalf("row %v nonce %v, want %v", i, r.Nonce, want)
+		}
+	}
+	if len(m.Gaps()) != 0 {
+		t.Fatalf("Gaps() = %v", m.Gaps())
+	}
+	if total := batch.Principal[mint.TokenMNT]; total.Value.Cmp(amount.FromInteger(1000*1001/2-501).Value) != 0 {
+		t.Fatalf("total %v", total)
+	}
+}
+
+func TestBuilder_ZeroNonce(t *testing.T) {
+	s, _ := signer.New()
+	payments := []Payment{{Address: mint.PublicKey{1}, Token: mint.TokenMNT, Amount: amount.FromInteger(1)}}
+	if _, err := (&Builder{Signer: s}).Build(payments); err == nil {
+		t.Fatal("Build() should fail on zero nonce")
+	}
+}
+
+func TestStall(t *testing.T) {
+	tx := &transaction.TransferAsset{}
+	results := []Result{
+		{Index: 0, Nonce: 1, Signed: &transaction.SignedTransaction{}},
+		{Index: 1, Err: errors.New("malformed")},
+		{Index: 2, Nonce: 3, Err: errors.New("sign")},
+		{Index: 3, Nonce: 2, Signed: &transaction.SignedTransaction{}},
+		{Index: 4, Nonce: 4, Signed: &transaction.SignedTransaction{}},
+	}
+	stall(results, []*transaction.TransferAsset{tx, nil, tx, tx, tx})
+	for i, stalled := range []bool{false, false, false, false, true} {
+		if got := errors.Is(results[i].Err, ErrStalled); got != stalled || got && results[i].Signed != nil {
+			t.Fatalf("row %v: %+v", i, results[i])
+		}
+	}
+}
+
+// failStore fails saving once fail is set
+type failStore struct {
+	*nonce.MemoryStore
+	fail bool
+}
+
+func (s *failStore) Save(addr mint.PublicKey, next uint64) error {
+	if s.fail {
+		return errors.New("disk full")
+	}
+	return s.MemoryStore.Save(addr, next)
+}
+
+func TestRelease(t *testing.T) {
+	addr := mint.PublicKey{1}
+	store := &failStore{MemoryStore: nonce.NewMemoryStore()}
+	m := nonce.New(store)
+	m.Sync(addr, 0)
+	m.Next(addr)
+	m.Next(addr)
+
+	store.fail = true
+	tx := &transaction.TransferAsset{}
+	results := []Result{
+		{Index: 0, Nonce: 1, Signed: &transaction.SignedTransaction{}},
+		{Index: 1, Nonce: 2, Err: ErrStalled},
+	}
+	release(m, addr, results, []*transaction.TransferAsset{tx, tx})
+	if results[0].Err != nil {
+		t.Fatalf("row 0: %v", results[0].Err)
+	}
+	if err := results[1].Err; !errors.Is(err, ErrStalled) || !strings.Contains(err.Error(), "disk full") {
+		t.Fatalf("row 1: %v", err)
+	}
+}
@@ -51,48 +51,105 @@ type CbkHeader func(*Header) error
 // CbkTransaction for parsed transaction
 type CbkTransaction func(transaction.Code, *serializer.Deserializer, *Header) error
 
+// CbkEnvelope for parsed transaction with its envelope
+type CbkEnvelope func(*transaction.Envelope, transaction.Transactioner, *transaction.ParsedTransaction, *Header) error
+
+// Envelopes adapts the envelope callback to be passed into the parser: every transaction data is read into an envelope.
+// The field and transaction size limits of the parser are applied
+func Envelopes(cbk CbkEnvelope) CbkTransaction {
+	return func(code transaction.Code, d *serializer.Deserializer, h *Header) error {
+		maxField, maxTotal := d.Limits()
+		limits := serializer.Limits{MaxFieldBytes: maxField}
+		if maxTotal > 0 {
+			limits.MaxTransactionBytes = maxTotal - d.Offset()
+		}
+		e, tx, ptx, err := transaction.ReadEnvelopeDataWithLimits(code, d.Source(), limits)
+		if err != nil {
+			return err
+		}
+		return cbk(e, tx, ptx, h)
+	}
+}
+
 // ---
 
-// Parse block
+// Parse block with default decoding limits (serializer.DefaultLimits)
 func Parse(r io.Reader, cbkHeader CbkHeader, cbkTransaction CbkTransaction) error {
-	d := serializer.NewStreamDeserializer(r)
+	return ParseWithLimits(r, serializer.DefaultLimits, cbkHeader, cbkTransaction)
+}
+
+// ParseWithLimits parses block with specified decoding limits
+func ParseWithLimits(r io.Reader, limits serializer.Limits, cbkHeader CbkHeader, cbkTransaction CbkTransaction) error {
 
 	// read header data into buffer to get it's digest later
-	headerData := bytes.NewBuffer(nil)
-	hd := serializer.NewStreamDeserializer(io.TeeReader(r, headerData))
+	headerData := &switchWriter{w: bytes.NewBuffer(nil)}
+	d := serializer.NewStreamDeserializer(io.TeeReader(r, headerData))
+
+	return parse(d, limits, func() []byte {
+		b := headerData.w.Bytes()
+		headerData.w = nil
+		return b
+	}, cbkHeader, cbkTransaction)
+}
+
+// ParseBytes parses block from a bytes slice with default decoding limits.
+// Bytes are not copied while parsing (see serializer.NewSliceDeserializer), so the data must not be modified until the parsing is completed
+func ParseBytes(data []byte, cbkHeader CbkHeader, cbkTransaction CbkTransaction) error {
+	d := serializer.NewSliceDeserializer(data)
+
+	return parse(d, serializer.DefaultLimits, func() []byte {
+		return data[:d.Offset()]
+	}, cbkHeader, cbkTransaction)
+}
+
+// ParseDeserializer parses block with the deserializer (see serializer package) starting at its current offset, the decoding limits are applied to the deserializer.
+// Header digest is calculated by encoding the header back (see Header.ComputeDigest).
+// Transactions are read inside the callback with the deserializer labeling fields with "Transactions[i]" prefix
+func ParseDeserializer(d *serializer.Deserializer, limits serializer.Limits, cbkHeader CbkHeader, cbkTransaction CbkTransaction) error {
+	return parse(d, limits, nil, cbkHeader, cbkTransaction)
+}
+
+// parse block, `headerData` returns raw header bytes read so far (nil to encode the header back)
+func parse(d *serializer.Deserializer, limits serializer.Limits, headerData func() []byte, cbkHeader CbkHeader, cbkTransaction CbkTransaction) error {
+	d.Limit(limits.MaxFieldBytes, limits.MaxBlockBytes)
 
 	// read header
 	header := &Header{}
-	header.Version = hd.GetUint16()         // version
-	header.PrevBlockDigest = hd.GetDigest() // previous block digest
-	header.ConsensusRound = hd.GetUint16()  // consensus round
-	header.MerkleRoot = hd.GetDigest()      // merkle root
+	header.Version = d.Field("Header.Version").GetUint16()                 // version
+	header.PrevBlockDigest = d.Field("Header.PrevBlockDigest").GetDigest() // previous block digest
+	header.ConsensusRound = d.Field("Header.ConsensusRound").GetUint16()   // consensus round
+	header.MerkleRoot = d.Field("Header.MerkleRoot").GetDigest()           // merkle root
 
 	// we should provide timestamp length (4 bytes, uint32, ) to calculate header digest (kinda bug in node's code)
-	headerData.WriteByte(8)
-	headerData.WriteByte(0)
-	headerData.WriteByte(0)
-	headerData.WriteByte(0)
+	timestampOffset := d.Offset()
 
 	// continue to read header
-	header.Timestamp = hd.GetUint64()         // time
-	header.TransactionsCount = hd.GetUint16() // transactions
-	header.BlockID = hd.GetUint256()          // block
-	if err := hd.Error(); err != nil {
+	header.Timestamp = d.Field("Header.Timestamp").GetUint64()                 // time
+	header.TransactionsCount = d.Field("Header.TransactionsCount").GetUint16() // transactions
+	if limits.MaxTransactions > 0 && header.TransactionsCount > limits.MaxTransactions {
+		d.Fail(&serializer.LimitError{Limit: serializer.LimitTransactions, Max: int64(limits.MaxTransactions), Got: int64(header.TransactionsCount)})
+	}
+	header.BlockID = d.Field("Header.BlockID").GetUint256() // block
+	if err := d.Error(); err != nil {
 		return err
 	}
 
 	// calc header digest
-	{
-		hasher := sha3.New256()
-		if _, err := hasher.Write(headerData.Bytes()); err != nil {
+	if headerData != nil {
+		header.Digest = headerDigest(headerData(), timestampOffset)
+	} else {
+		digest, err := header.ComputeDigest()
+		if err != nil {
 			return err
 		}
-		copy(header.Digest[:], hasher.Sum(nil))
+		header.Digest = digest
 	}
 
 	// continue to read header
-	header.SignersCount = d.GetUint16() // signers
+	header.SignersCount = d.Field("Header.SignersCount").GetUint16() // signers
+	if limits.MaxSigners > 0 && header.SignersCount > limits.MaxSigners {
+		d.Fail(&serializer.LimitError{Limit: serializer.LimitSigners, Max: int64(limits.MaxSigners), Got: int64(header.SignersCount)})
+	}
 	if err := d.Error(); err != nil {
 		return err
 	}
@@ -102,8 +159,8 @@ func Parse(r io.Reader, cbkHeader CbkHeader, cbkTransaction CbkTransaction) erro
 	for i := uint16(0); i < header.SignersCount; i++ {
 
 		sig := Signer{}
-		sig.PublicKey = d.GetPublicKey() // address
-		sig.Signature = d.GetSignature() // signature
+		sig.PublicKey = d.Field(fmt.Sprintf("Header.Signers[%v].PublicKey", i)).GetPublicKey() // address
+		sig.Signature = d.Field(fmt.Sprintf("Header.Signers[%v].Signature", i)).GetSignature() // signature
 
 		if err := d.Error(); err != nil {
 			return err
@@ -119,7 +176,7 @@ func Parse(r io.Reader, cbkHeader CbkHeader, cbkTransaction CbkTransaction) erro
 	// read transactions
 	for i := uint16(0); i < header.TransactionsCount; i++ {
 
-		code := d.GetUint16() // code
+		code := d.Field(fmt.Sprintf("Transactions[%v].Code", i)).GetUint16() // code
 		if err := d.Error(); err != nil {
 			return err
 		}
@@ -130,8 +187,19 @@ func Parse(r io.Reader, cbkHeader CbkHeader, cbkTransaction CbkTransaction) erro
 		}
 		txCode := transaction.Code(code)
 
-		// parse transaction outside
-		if err := cbkTransaction(txCode, d, header); err != nil {
+		// parse transaction outside, the total limit is narrowed to the transaction size limit
+		prefix := d.LabelPrefix()
+		maxField, maxTotal := d.Limits()
+		if limits.MaxTransactionBytes > 0 {
+			if end := d.Offset() + limits.MaxTransactionBytes; maxTotal == 0 || end < maxTotal {
+				d.Limit(maxField, end)
+			}
+		}
+		d.Prefix(fmt.Sprintf("Transactions[%v]", i))
+		err := cbkTransaction(txCode, d, header)
+		d.Prefix(prefix)
+		d.Limit(maxField, maxTotal)
+		if err != nil {
 			return err
 		}
 		if err := d.Error(); err != nil {
@@ -141,3 +209,64 @@ func Parse(r io.Reader, cbkHeader CbkHeader, cbkTransaction CbkTransaction) erro
 
 	return nil
 }
+
+// Encode header into bytes, signers count is taken from the signers list (SignersCount is ignored).
+// Transactions count is encoded as is
+func (h *Header) Encode() ([]byte, error) {
+	ser, _ := h.serialize()
+	if len(h.Signers) > 0xFFFF {
+		return nil, fmt.Errorf("too many signers, got %v", len(h.Signers))
+	}
+	ser.PutUint16(uint16(len(h.Signers))) // signers
+	for _, sig := range h.Signers {
+		ser.PutPublicKey(sig.PublicKey) // address
+		ser.PutSignature(sig.Signature) // signature
+	}
+	return ser.Data()
+}
+
+// ComputeDigest calculates header digest from the header fields (Digest field is not used)
+func (h *Header) ComputeDigest() (mint.Digest, error) {
+	ser, timestampOffset := h.serialize()
+	b, err := ser.Data()
+	if err != nil {
+		return mint.Digest{}, err
+	}
+	return headerDigest(b, timestampOffset), nil
+}
+
+// serialize header up to signers list, returns timestamp offset to calculate header digest
+func (h *Header) serialize() (*serializer.Serializer, int64) {
+	ser := serializer.NewSerializer()
+	ser.PutUint16(h.Version)         // version
+	ser.PutDigest(h.PrevBlockDigest) // previous block digest
+	ser.PutUint16(h.ConsensusRound)  // consensus round
+	ser.PutDigest(h.MerkleRoot)      // merkle root
+	timestampOffset := int64(2 + mint.DigestSize + 2 + mint.DigestSize)
+	ser.PutUint64(h.Timestamp)         // time
+	ser.PutUint16(h.TransactionsCount) // transactions
+	ser.PutUint256(h.BlockID)          // block
+	return ser, timestampOffset
+}
+
+// headerDigest calculates header digest from raw header bytes (up to signers count)
+func headerDigest(b []byte, timestampOffset int64) (ret mint.Digest) {
+	hasher := sha3.New256()
+	hasher.Write(b[:timestampOffset])
+	hasher.Write([]byte{8, 0, 0, 0})
+	hasher.Write(b[timestampOffset:])
+	copy(ret[:], hasher.Sum(nil))
+	return
+}
+
+// switchWriter writes into the buffer until it's nil
+type switchWriter struct {
+	w *bytes.Buffer
+}
+
+func (s *switchWriter) Write(p []byte) (int, error) {
+	if s.w == nil {
+		return len(p), nil
+	}
+	return s.w.Write(p)
+}
@@ -0,0 +1,263 @@
+package block
+
+import (
+	"bytes"
+	"errors"
+	"math/big"
+	"math/rand"
+	"reflect"
+	"testing"
+
+	mint "github.com/void616/gm.mint"
+	"github.com/void616/gm.mint/amount"
+	"github.com/void616/gm.mint/serializer"
+	"github.com/void616/gm.mint/signer"
+	"github.com/void616/gm.mint/transaction"
+	"golang.org/x/crypto/sha3"
+)
+
+// testBlock makes a block with `signers` signers (only 64 of them are written) and a transfer transaction
+func testBlock(t testing.TB, signers uint16) []byte {
+	return testBlockN(t, signers, 1)
+}
+
+// testBlockN makes a block with `signers` signers (only 64 of them are written) and `txs` transfer transactions
+func testBlockN(t testing.TB, signers, txs uint16) []byte {
+	s, _ := signer.New()
+	dst, _ := signer.New()
+	tx, err := (&transaction.TransferAsset{
+		Address: dst.PublicKey(),
+		Token:   mint.TokenGOLD,
+		Amount:  amount.MustFromString("1.666"),
+	}).Sign(s, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ser := serializer.NewSerializer()
+	ser.PutUint16(1)                            // version
+	ser.PutBytes(make([]byte, mint.DigestSize)) // previous block digest
+	ser.PutUint16(0)                            // consensus round
+	ser.PutBytes(make([]byte, mint.DigestSize)) // merkle root
+	ser.PutUint64(19527035308000000)            // time
+	ser.PutUint16(txs)                          // transactions
+	ser.PutBytes(make([]byte, 32))              // block
+	ser.PutUint16(signers)                      // signers
+	for i := uint16(0); i < signers && i < 64; i++ {
+		ser.PutPublicKey(s.PublicKey())
+		ser.PutBytes(make([]byte, mint.SignatureSize))
+	}
+	for i := uint16(0); i < txs; i++ {
+		ser.PutUint16(uint16(transaction.TransferAssetTx))
+		ser.PutBytes(tx.Data)
+	}
+	b, err := ser.Data()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+// parseTx parses a transaction of the block
+func parseTx(code transaction.Code, d *serializer.Deserializer, h *Header) error {
+	tx, err := transaction.CodeToTransaction(code)
+	if err != nil {
+		return err
+	}
+	_, err = tx.Parse(d.Source())
+	return err
+}
+
+func TestParse(t *testing.T) {
+	dat := testBlock(t, 2)
+
+	var header *Header
+	var txs int
+	err := Parse(bytes.NewReader(dat), func(h *Header) error {
+		header = h
+		return nil
+	}, func(code transaction.Code, d *serializer.Deserializer, h *Header) error {
+		txs++
+		return parseTx(code, d, h)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if header.SignersCount != 2 || len(header.Signers) != 2 || header.BlockID.Cmp(big.NewInt(0)) != 0 || txs != 1 {
+		t.Fatalf("Parse() header = %#v, transactions %v", header, txs)
+	}
+
+	// header digest
+	hasher := sha3.New256()
+	hasher.Write(dat[:68])
+	hasher.Write([]byte{8, 0, 0, 0})
+	hasher.Write(dat[68:110])
+	if !bytes.Equal(header.Digest[:], hasher.Sum(nil)) {
+		t.Fatal("Parse() header digest mismatch")
+	}
+
+	// the same from bytes
+	var header2 *Header
+	err = ParseBytes(dat, func(h *Header) error {
+		header2 = h
+		return nil
+	}, parseTx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(header, header2) {
+		t.Fatalf("ParseBytes() header = %#v, want %#v", header2, header)
+	}
+
+	// the same with a deserializer, transactions are parsed in place
+	var header3 *Header
+	err = ParseDeserializer(serializer.NewDeserializer(dat), serializer.DefaultLimits, func(h *Header) error {
+		header3 = h
+		return nil
+	}, func(code transaction.Code, d *serializer.Deserializer, h *Header) error {
+		if d.LabelPrefix() != "Transactions[0]" {
+			t.Errorf("ParseDeserializer() transaction prefix = %v", d.LabelPrefix())
+		}
+		tx, err := transaction.CodeToTransaction(code)
+		if err != nil {
+			return err
+		}
+		_, err = transaction.ParseDeserializer(tx, d)
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(header, header3) {
+		t.Fatalf("ParseDeserializer() header = %#v, want %#v", header3, header)
+	}
+}
+
+func TestHeader_Encode(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+	for i := 0; i < 100; i++ {
+		// random header bytes (block ID is any 256-bit value)
+		signers := uint16(rnd.Intn(4))
+		b := make([]byte, 110+2+int(signers)*(mint.PublicKeySize+mint.SignatureSize))
+		rnd.Read(b)
+		b[110], b[111] = byte(signers), 0
+
+		var header *Header
+		err := ParseBytes(b, func(h *Header) error {
+			header = h
+			return errors.New("stop")
+		}, nil)
+		if header == nil {
+			t.Fatalf("ParseBytes() error = %v", err)
+		}
+
+		enc, err := header.Encode()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(enc, b) {
+			t.Fatalf("Encode() = %x, want %x", enc, b)
+		}
+		digest, err := header.ComputeDigest()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if digest != header.Digest {
+			t.Fatalf("ComputeDigest() = %v, want %v", digest, header.Digest)
+		}
+	}
+
+	if _, err := (&Header{}).Encode(); err == nil {
+		t.Fatal("Encode() should fail on nil block ID")
+	}
+}
+
+func TestEnvelopes(t *testing.T) {
+	dat := testBlockN(t, 1, 3)
+
+	var envelopes []*transaction.Envelope
+	err := ParseBytes(dat, func(*Header) error { return nil }, Envelopes(func(e *transaction.Envelope, tx transaction.Transactioner, ptx *transaction.ParsedTransaction, h *Header) error {
+		if _, ok := tx.(*transaction.TransferAsset); !ok || ptx.Nonce != 1 {
+			t.Errorf("Envelopes() transaction = %#v, %+v", tx, ptx)
+		}
+		envelopes = append(envelopes, e)
+		return nil
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(envelopes) != 3 {
+		t.Fatalf("Envelopes() got %v envelopes", len(envelopes))
+	}
+
+	// envelopes are encoded exactly as in the block
+	b, err := envelopes[2].Encode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.HasSuffix(dat, b) {
+		t.Fatal("Encode() differs from the block data")
+	}
+}
+
+func TestParseWithLimits(t *testing.T) {
+	nop := func(*Header) error { return nil }
+	nopTx := func(transaction.Code, *serializer.Deserializer, *Header) error { return nil }
+
+	limits := serializer.DefaultLimits
+	limits.MaxSigners = 16
+
+	err := ParseWithLimits(bytes.NewReader(testBlock(t, 0xFFFF)), limits, nop, nopTx)
+	var lerr *serializer.LimitError
+	if !errors.As(err, &lerr) || lerr.Limit != serializer.LimitSigners {
+		t.Fatalf("ParseWithLimits() error = %v, want signers limit error", err)
+	}
+
+	limits = serializer.DefaultLimits
+	limits.MaxBlockBytes = 200
+	err = ParseWithLimits(bytes.NewReader(testBlock(t, 2)), limits, nop, nopTx)
+	if !errors.As(err, &lerr) || lerr.Limit != serializer.LimitTotal {
+		t.Fatalf("ParseWithLimits() error = %v, want total limit error", err)
+	}
+
+	// transaction limits reach the transaction parser
+	envelopes := Envelopes(func(*transaction.Envelope, transaction.Transactioner, *transaction.ParsedTransaction, *Header) error {
+		return nil
+	})
+	limits = serializer.DefaultLimits
+	limits.MaxTransactionBytes = 50
+	err = ParseWithLimits(bytes.NewReader(testBlock(t, 2)), limits, nop, envelopes)
+	if !errors.As(err, &lerr) || lerr.Limit != serializer.LimitTotal {
+		t.Fatalf("ParseWithLimits() error = %v, want transaction limit error", err)
+	}
+	limits.MaxTransactionBytes = 0
+	if err := ParseWithLimits(bytes.NewReader(testBlock(t, 2)), limits, nop, envelopes); err != nil {
+		t.Fatalf("ParseWithLimits() error = %v", err)
+	}
+}
+
+func BenchmarkParse(b *testing.B) {
+	dat := testBlockN(b, 16, 1000)
+	nop := func(*Header) error { return nil }
+	b.SetBytes(int64(len(dat)))
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if err := Parse(bytes.NewReader(dat), nop, parseTx); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkParseBytes(b *testing.B) {
+	dat := testBlockN(b, 16, 1000)
+	nop := func(*Header) error { return nil }
+	b.SetBytes(int64(len(dat)))
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if err := ParseBytes(dat, nop, parseTx); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,149 @@
+package block
+
+import (
+	"fmt"
+	"math/big"
+
+	mint "github.com/void616/gm.mint"
+)
+
+// AnomalyKind is a kind of chain continuity violation
+type AnomalyKind string
+
+const (
+	// AnomalyFork means the block doesn't link to the previous one: PrevBlockDigest differs from the previous block digest,
+	// or the block has the same ID as the previous one but a different digest
+	AnomalyFork AnomalyKind = "fork"
+	// AnomalyGap means blocks between the previous one and the block are missing
+	AnomalyGap AnomalyKind = "gap"
+	// AnomalyReorder means the block ID is not greater than the previous one (reordered or repeated block)
+	AnomalyReorder AnomalyKind = "reorder"
+	// AnomalyTime means the block timestamp is before the previous block timestamp
+	AnomalyTime AnomalyKind = "time"
+)
+
+// Anomaly of the chain continuity
+type Anomaly struct {
+	Kind AnomalyKind
+	// Header of the checked block
+	Header *Header
+	// Prev is the previous block
+	Prev Checkpoint
+}
+
+// Error impl
+func (a *Anomaly) Error() string {
+	h, p := a.Header, a.Prev
+	switch a.Kind {
+	case AnomalyFork:
+		if h.BlockID.Cmp(p.BlockID) == 0 {
+			return fmt.Sprintf("block %v: differs from the known block with digest %v", h.BlockID, p.Digest)
+		}
+		return fmt.Sprintf("block %v: previous block digest %v differs from block %v digest %v", h.BlockID, h.PrevBlockDigest, p.BlockID, p.Digest)
+	case AnomalyGap:
+		return fmt.Sprintf("block %v: blocks after %v are missing", h.BlockID, p.BlockID)
+	case AnomalyReorder:
+		return fmt.Sprintf("block %v: out of order after block %v", h.BlockID, p.BlockID)
+	case AnomalyTime:
+		return fmt.Sprintf("block %v: timestamp %v is before block %v timestamp %v", h.BlockID, h.Timestamp, p.BlockID, p.Timestamp)
+	}
+	return fmt.Sprintf("block %v: %v", h.BlockID, a.Kind)
+}
+
+// Checkpoint is the last accepted block of the chain validation, the validation could be resumed from it
+type Checkpoint struct {
+	BlockID   *big.Int    `json:"block_id"`
+	Digest    mint.Digest `json:"digest"`
+	Timestamp uint64      `json:"timestamp"`
+}
+
+// ChainValidator checks continuity of consecutive block headers: PrevBlockDigest links to the previous block digest,
+// BlockID increments by one and Timestamp is non-decreasing.
+// A reordered or repeated block is reported and skipped, other blocks become the previous one even if anomalous
+type ChainValidator struct {
+	prev *Checkpoint
+}
+
+// NewChainValidator resumes the validation from the checkpoint, the first header is trusted if the checkpoint is nil (or has no block ID)
+func NewChainValidator(cp *Checkpoint) *ChainValidator {
+	v := &ChainValidator{}
+	if cp != nil && cp.BlockID != nil {
+		v.prev = copyCheckpoint(cp)
+	}
+	return v
+}
+
+// Checkpoint is the last accepted block, nil if there is no one yet
+func (v *ChainValidator) Checkpoint() *Checkpoint {
+	if v.prev == nil {
+		return nil
+	}
+	return copyCheckpoint(v.prev)
+}
+
+// Check the header against the previous one. The header digest is computed if it's empty
+func (v *ChainValidator) Check(h *Header) ([]*Anomaly, error) {
+	if h.BlockID == nil {
+		return nil, fmt.Errorf("block ID is nil")
+	}
+	digest := h.Digest
+	if digest == (mint.Digest{}) {
+		d, err := h.ComputeDigest()
+		if err != nil {
+			return nil, err
+		}
+		digest = d
+	}
+
+	cp := &Checkpoint{BlockID: new(big.Int).Set(h.BlockID), Digest: digest, Timestamp: h.Timestamp}
+	if v.prev == nil {
+		v.prev = cp
+		return nil, nil
+	}
+
+	var ret []*Anomaly
+	report := func(k AnomalyKind) {
+		ret = append(ret, &Anomaly{Kind: k, Header: h, Prev: *copyCheckpoint(v.prev)})
+	}
+	next := new(big.Int).Add(v.prev.BlockID, big.NewInt(1))
+	switch c := h.BlockID.Cmp(next); {
+	case c < 0:
+		if h.BlockID.Cmp(v.prev.BlockID) == 0 && digest != v.prev.Digest {
+			report(AnomalyFork)
+		} else {
+			report(AnomalyReorder)
+		}
+		return ret, nil
+	case c > 0:
+		report(AnomalyGap)
+	default:
+		if h.PrevBlockDigest != v.prev.Digest {
+			report(AnomalyFork)
+		}
+	}
+	if h.Timestamp < v.prev.Timestamp {
+		report(AnomalyTime)
+	}
+	v.prev = cp
+	return ret, nil
+}
+
+// Headers adapts the validator to be passed into the parser (see Parse) as a header callback,
+// `cbk` gets every header with its anomalies
+func (v *ChainValidator) Headers(cbk func(*Header, []*Anomaly) error) CbkHeader {
+	return func(h *Header) error {
+		anomalies, err := v.Check(h)
+		if err != nil {
+			return err
+		}
+		return cbk(h, anomalies)
+	}
+}
+
+func copyCheckpoint(cp *Checkpoint) *Checkpoint {
+	ret := *cp
+	if cp.BlockID != nil {
+		ret.BlockID = new(big.Int).Set(cp.BlockID)
+	}
+	return &ret
+}
@@ -0,0 +1,135 @@
+package block
+
+import (
+	"encoding/json"
+	"math/big"
+	"reflect"
+	"testing"
+
+	mint "github.com/void616/gm.mint"
+)
+
+// testChain makes n linked headers starting from block 0
+func testChain(t *testing.T, n int) []*Header {
+	var ret []*Header
+	var prev mint.Digest
+	for i := 0; i < n; i++ {
+		h := &Header{
+			Version:         1,
+			PrevBlockDigest: prev,
+			Timestamp:       uint64(1000 + i),
+			BlockID:         big.NewInt(int64(i)),
+			Signers:         []Signer{},
+		}
+		d, err := h.ComputeDigest()
+		if err != nil {
+			t.Fatal(err)
+		}
+		h.Digest, prev = d, d
+		ret = append(ret, h)
+	}
+	return ret
+}
+
+func kinds(t *testing.T, v *ChainValidator, h *Header) []AnomalyKind {
+	t.Helper()
+	list, err := v.Check(h)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var ret []AnomalyKind
+	for _, a := range list {
+		if a.Error() == "" {
+			t.Fatal("empty anomaly message")
+		}
+		ret = append(ret, a.Kind)
+	}
+	return ret
+}
+
+func TestChainValidator(t *testing.T) {
+	chain := testChain(t, 6)
+
+	// fork: block 2 of another chain, the same ID of the previous block but different digest
+	fork := *chain[2]
+	fork.MerkleRoot = mint.Digest{1}
+	fork.Digest = mint.Digest{}
+	// time goes backwards
+	early := *chain[5]
+	early.Timestamp = 1
+
+	tests := []struct {
+		h    *Header
+		want []AnomalyKind
+	}{
+		{chain[0], nil},
+		{chain[1], nil},
+		{chain[3], []AnomalyKind{AnomalyGap}},
+		{chain[2], []AnomalyKind{AnomalyReorder}},
+		{chain[3], []AnomalyKind{AnomalyReorder}},
+		{&fork, []AnomalyKind{AnomalyReorder}},
+		{chain[4], nil},
+		{&early, []AnomalyKind{AnomalyTime}},
+	}
+	v := NewChainValidator(nil)
+	for i, tt := range tests {
+		if got := kinds(t, v, tt.h); !reflect.DeepEqual(got, tt.want) {
+			t.Fatalf("header %v (block %v): got %v, want %v", i, tt.h.BlockID, got, tt.want)
+		}
+	}
+
+	// fork of the previous block and a broken link
+	v = NewChainValidator(nil)
+	kinds(t, v, chain[2])
+	if got := kinds(t, v, &fork); !reflect.DeepEqual(got, []AnomalyKind{AnomalyFork}) {
+		t.Fatalf("fork: got %v", got)
+	}
+	link := *chain[3]
+	link.PrevBlockDigest = mint.Digest{1}
+	if got := kinds(t, v, &link); !reflect.DeepEqual(got, []AnomalyKind{AnomalyFork}) {
+		t.Fatalf("link: got %v", got)
+	}
+}
+
+func TestChainValidator_Checkpoint(t *testing.T) {
+	chain := testChain(t, 4)
+	v := NewChainValidator(nil)
+	if v.Checkpoint() != nil {
+		t.Fatal("checkpoint of empty validator")
+	}
+	for _, h := range chain[:2] {
+		kinds(t, v, h)
+	}
+
+	// resume from JSON
+	b, err := json.Marshal(v.Checkpoint())
+	if err != nil {
+		t.Fatal(err)
+	}
+	cp := &Checkpoint{}
+	if err := json.Unmarshal(b, cp); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(cp, v.Checkpoint()) {
+		t.Fatalf("checkpoint %+v", cp)
+	}
+	v = NewChainValidator(cp)
+	for _, h := range chain[2:] {
+		if got := kinds(t, v, h); got != nil {
+			t.Fatalf("block %v: %v", h.BlockID, got)
+		}
+	}
+	if got := v.Checkpoint(); got.BlockID.Int64() != 3 || got.Digest != chain[3].Digest {
+		t.Fatalf("checkpoint %+v", got)
+	}
+
+	// headers callback
+	var got []*Anomaly
+	cbk := NewChainValidator(cp).Headers(func(h *Header, list []*Anomaly) error {
+		got = append(got, list...)
+		return nil
+	})
+	if err := cbk(chain[3]); err != nil || len(got) != 1 || got[0].Kind != AnomalyGap {
+		t.Fatalf("callback: %v, %v", got, err)
+	}
+}
@@ -0,0 +1,78 @@
+// Command mintinspect prints an annotated wire layout of a transaction or a block.
+//
+// Usage:
+//
+//	mintinspect -tx transfer_asset [-json] [-enc hex|base58] <hex or base58 data>
+//	mintinspect -block [-json] [-enc hex|base58] < block.hex
+//
+// The encoding is detected if -enc is omitted, hex which is also a valid base58 must be prefixed with 0x then
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+
+	"github.com/void616/gm.mint/inspect"
+	"github.com/void616/gm.mint/transaction"
+)
+
+func main() {
+	var (
+		txName  = flag.String("tx", "", "transaction code name, like transfer_asset")
+		isBlock = flag.Bool("block", false, "data is a block")
+		asJSON  = flag.Bool("json", false, "JSON output")
+		enc     = flag.String("enc", "", "data encoding: hex or base58, detected if omitted")
+	)
+	flag.Parse()
+
+	if (*txName == "") == !*isBlock {
+		fail(fmt.Errorf("specify either -tx or -block"))
+	}
+
+	// data from the argument or stdin
+	var raw string
+	if flag.NArg() > 0 {
+		raw = flag.Arg(0)
+	} else {
+		b, err := ioutil.ReadAll(os.Stdin)
+		if err != nil {
+			fail(err)
+		}
+		raw = string(b)
+	}
+	data, err := inspect.Decode(raw, inspect.Encoding(*enc))
+	if err != nil {
+		fail(err)
+	}
+
+	var r *inspect.Report
+	if *isBlock {
+		r = inspect.Block(data)
+	} else {
+		code, err := transaction.ParseCode(*txName)
+		if err != nil {
+			fail(err)
+		}
+		r = inspect.Transaction(code, data)
+	}
+
+	if *asJSON {
+		b, err := r.JSON()
+		if err != nil {
+			fail(err)
+		}
+		fmt.Println(string(b))
+	} else if err := r.WriteText(os.Stdout); err != nil {
+		fail(err)
+	}
+	if r.Error != "" || r.Trailing > 0 {
+		os.Exit(1)
+	}
+}
+
+func fail(err error) {
+	fmt.Fprintln(os.Stderr, err)
+	os.Exit(2)
+}
@@ -0,0 +1,51 @@
+package devnet
+
+import (
+	"sync"
+	"time"
+)
+
+// Clock is a source of block timestamps
+type Clock interface {
+	Now() time.Time
+}
+
+// SystemClock is the local time
+type SystemClock struct{}
+
+// Now impl
+func (SystemClock) Now() time.Time {
+	return time.Now()
+}
+
+// ManualClock is a clock moved by hand, it's safe for concurrent use
+type ManualClock struct {
+	mu sync.Mutex
+	t  time.Time
+}
+
+// NewManualClock set to the time
+func NewManualClock(t time.Time) *ManualClock {
+	return &ManualClock{t: t}
+}
+
+// Now impl
+func (c *ManualClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.t
+}
+
+// Set the time
+func (c *ManualClock) Set(t time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.t = t
+}
+
+// Advance the time by the duration
+func (c *ManualClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.t = c.t.Add(d)
+}
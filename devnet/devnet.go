@@ -0,0 +1,509 @@
+package devnet
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"sync"
+	"time"
+
+	mint "github.com/void616/gm.mint"
+	"github.com/void616/gm.mint/amount"
+	"github.com/void616/gm.mint/block"
+	"github.com/void616/gm.mint/fee"
+	"github.com/void616/gm.mint/policy"
+	"github.com/void616/gm.mint/signer"
+	"github.com/void616/gm.mint/transaction"
+	"github.com/void616/gm.mint/wallet"
+	"golang.org/x/crypto/sha3"
+)
+
+// Errors of Submit
+var (
+	// ErrUnsigned means the transaction has no signature
+	ErrUnsigned = errors.New("transaction is not signed")
+	// ErrSignature means the signature is invalid
+	ErrSignature = errors.New("transaction signature is invalid")
+	// ErrNonceUsed means the nonce is already included into the chain
+	ErrNonceUsed = errors.New("transaction nonce is already used")
+	// ErrNoncePending means another pending transaction of the sender has the same nonce
+	ErrNoncePending = errors.New("transaction nonce is already pending")
+)
+
+// Genesis is an initial state of the chain
+type Genesis struct {
+	// Time of the genesis block, the clock time if zero
+	Time time.Time
+	// Accounts by address
+	Accounts map[mint.PublicKey]Allocation
+}
+
+// Allocation is an initial state of an address
+type Allocation struct {
+	Balances fee.Balances
+	Tags     []mint.WalletTag
+}
+
+// Config of the chain
+type Config struct {
+	// Genesis state
+	Genesis Genesis
+	// Validators sign every block, a random validator is used if empty
+	Validators []*signer.Signer
+	// Schedules of the fee resolved per block, the default schedule if empty
+	Schedules fee.Schedules
+	// Policy of the wallet tags, transactions are not authorized and nobody is fee-free if nil
+	Policy *policy.Policy
+	// Clock of the block timestamps, the system clock if nil
+	Clock Clock
+	// MaxTransactions per block, the header limit (65535) if zero
+	MaxTransactions int
+}
+
+// State of a transaction
+type State string
+
+const (
+	// StatePending means the transaction is accepted, but not included into a block yet
+	StatePending State = "pending"
+	// StateIncluded means the transaction is included into a block
+	StateIncluded State = "included"
+	// StateRejected means the transaction is accepted, but failed to apply (like insufficient funds). Its nonce is consumed
+	StateRejected State = "rejected"
+)
+
+// Status of a transaction
+type Status struct {
+	ID    transaction.TxID
+	State State
+	// BlockID, if included
+	BlockID *big.Int
+	// Err, if rejected
+	Err error
+}
+
+// Chain is an in-memory chain: it accepts signed transactions and seals them into blocks readable by block.Parse.
+// Blocks are sealed by hand (Seal) or by timer (Run). It implements wallet.Chain, wallet.Submitter and wallet.Tracker.
+//
+// TransferAsset moves the principal, Set/UnsetWalletTag change the tags, other transactions only spend the fee.
+// Fees are burned, fee-free senders (see policy.Policy.IsFeeFree) pay nothing.
+// A transaction failed to apply consumes its nonce, so the following transactions of the sender aren't stalled.
+//
+// Header MerkleRoot is a binary SHA3-256 tree of the transaction digests (see merkleRoot), zero for an empty block.
+// It's consistent within the devnet, but it's not checked to match the root computed by Sumus nodes
+type Chain struct {
+	mu         sync.Mutex
+	cfg        Config
+	validators []*signer.Signer
+	accounts   map[mint.PublicKey]*account
+	pending    []*tx
+	txs        map[transaction.TxID]*tx
+	blocks     [][]byte
+	headers    []*block.Header
+}
+
+type account struct {
+	balances fee.Balances
+	nonce    uint64
+	tags     []mint.WalletTag
+}
+
+type tx struct {
+	envelope *transaction.Envelope
+	tx       transaction.Transactioner
+	ptx      *transaction.ParsedTransaction
+	status   Status
+}
+
+var (
+	_ wallet.Chain     = (*Chain)(nil)
+	_ wallet.Submitter = (*Chain)(nil)
+	_ wallet.Tracker   = (*Chain)(nil)
+)
+
+// New chain with the genesis block (ID 0)
+func New(cfg Config) (*Chain, error) {
+	if cfg.Clock == nil {
+		cfg.Clock = SystemClock{}
+	}
+	if cfg.MaxTransactions <= 0 || cfg.MaxTransactions > math.MaxUint16 {
+		cfg.MaxTransactions = math.MaxUint16
+	}
+	if cfg.Policy != nil {
+		if err := cfg.Policy.Validate(); err != nil {
+			return nil, fmt.Errorf("policy: %v", err)
+		}
+	}
+	c := &Chain{
+		cfg:        cfg,
+		validators: cfg.Validators,
+		accounts:   make(map[mint.PublicKey]*account),
+		txs:        make(map[transaction.TxID]*tx),
+	}
+	if len(c.validators) == 0 {
+		s, err := signer.New()
+		if err != nil {
+			return nil, err
+		}
+		c.validators = []*signer.Signer{s}
+	}
+	for addr, alloc := range cfg.Genesis.Accounts {
+		acc := c.account(addr)
+		for t, a := range alloc.Balances {
+			if !mint.ValidToken(uint16(t)) {
+				return nil, fmt.Errorf("genesis: invalid token %v of %v", t, addr)
+			}
+			if a == nil || a.Value.Sign() < 0 {
+				return nil, fmt.Errorf("genesis: invalid %v balance of %v", t, addr)
+			}
+			acc.balances[t] = amount.FromAmount(a)
+		}
+		for _, tag := range alloc.Tags {
+			if !mint.ValidWalletTag(uint8(tag)) {
+				return nil, fmt.Errorf("genesis: invalid tag %v of %v", tag, addr)
+			}
+			acc.tags = append(removeTag(acc.tags, tag), tag)
+		}
+	}
+	t := cfg.Genesis.Time
+	if t.IsZero() {
+		t = cfg.Clock.Now()
+	}
+	if _, err := c.seal(nil, mint.TimeToStamp(t)); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// Submit accepts the transaction (see SubmitEnvelope), an error is wallet.RejectedError
+func (c *Chain) Submit(ctx context.Context, e *transaction.Envelope) error {
+	if _, err := c.SubmitEnvelope(ctx, e); err != nil {
+		return &wallet.RejectedError{Err: err}
+	}
+	return nil
+}
+
+// SubmitEnvelope verifies the signature, validates the transaction against the current state (nonce, tags and balances)
+// and accepts it until the next block. Resubmission of a pending or included transaction succeeds, a rejected one is validated again
+func (c *Chain) SubmitEnvelope(ctx context.Context, e *transaction.Envelope) (transaction.TxID, error) {
+	t, ptx, err := e.Open()
+	if err != nil {
+		return transaction.TxID{}, err
+	}
+	if !ptx.Signed {
+		return transaction.TxID{}, ErrUnsigned
+	}
+	if err := signer.Verify(ptx.From, ptx.Digest[:], ptx.Signature); err != nil {
+		return transaction.TxID{}, fmt.Errorf("%w: %v", ErrSignature, err)
+	}
+	if err := transaction.ValidateSender(t, ptx.From); err != nil {
+		return transaction.TxID{}, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	id := ptx.ID()
+	if x, ok := c.txs[id]; ok && x.status.State != StateRejected {
+		return id, nil
+	}
+	acc := c.account(ptx.From)
+	if ptx.Nonce <= acc.nonce {
+		return transaction.TxID{}, fmt.Errorf("%w: nonce %v, the last one is %v", ErrNonceUsed, ptx.Nonce, acc.nonce)
+	}
+	for _, p := range c.pending {
+		if p.ptx.From == ptx.From && p.ptx.Nonce == ptx.Nonce {
+			return transaction.TxID{}, fmt.Errorf("%w: nonce %v", ErrNoncePending, ptx.Nonce)
+		}
+	}
+	schedule := c.cfg.Schedules.Resolve(uint64(len(c.headers)), c.timestamp())
+	if _, err := c.spend(t, acc, schedule); err != nil {
+		return transaction.TxID{}, err
+	}
+
+	x := &tx{
+		envelope: &transaction.Envelope{Code: e.Code, Data: append([]byte(nil), e.Data...)},
+		tx:       t,
+		ptx:      ptx,
+		status:   Status{ID: id, State: StatePending},
+	}
+	c.txs[id] = x
+	c.pending = append(c.pending, x)
+	return id, nil
+}
+
+// Status of the transaction
+func (c *Chain) Status(id transaction.TxID) (*Status, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	x, ok := c.txs[id]
+	if !ok {
+		return nil, false
+	}
+	s := x.status
+	return &s, true
+}
+
+// Known returns false if the transaction is unknown or rejected (see wallet.Tracker)
+func (c *Chain) Known(ctx context.Context, id transaction.TxID) (bool, error) {
+	st, ok := c.Status(id)
+	return ok && st.State != StateRejected, nil
+}
+
+// Account gets the state of the address, an unknown address has zero state
+func (c *Chain) Account(ctx context.Context, addr mint.PublicKey) (*wallet.Account, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	acc := c.account(addr)
+	ret := &wallet.Account{
+		Balances: fee.Balances{},
+		Nonce:    acc.nonce,
+		Tags:     append([]mint.WalletTag{}, acc.tags...),
+	}
+	for t, a := range acc.balances {
+		ret.Balances[t] = amount.FromAmount(a)
+	}
+	return ret, nil
+}
+
+// Balance of the address
+func (c *Chain) Balance(addr mint.PublicKey, token mint.Token) *amount.Amount {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if a, ok := c.account(addr).balances[token]; ok {
+		return amount.FromAmount(a)
+	}
+	return amount.New()
+}
+
+// Latest block header, it must not be modified
+func (c *Chain) Latest() *block.Header {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.headers[len(c.headers)-1]
+}
+
+// Block data by ID (see block.Parse)
+func (c *Chain) Block(id uint64) ([]byte, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if id >= uint64(len(c.blocks)) {
+		return nil, false
+	}
+	return append([]byte(nil), c.blocks[id]...), true
+}
+
+// Seal a block of the pending transactions at the clock time: the ones following the sender's last nonce are applied
+// (or rejected consuming the nonce, like on insufficient funds), others stay pending. An empty block is sealed if nothing is applied
+func (c *Chain) Seal() (*block.Header, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	ts := c.timestamp()
+	schedule := c.cfg.Schedules.Resolve(uint64(len(c.headers)), ts)
+
+	var included []*tx
+	for progress := true; progress && len(included) < c.cfg.MaxTransactions; {
+		progress = false
+		rest := c.pending[:0]
+		for _, x := range c.pending {
+			acc := c.account(x.ptx.From)
+			if x.ptx.Nonce != acc.nonce+1 || len(included) >= c.cfg.MaxTransactions {
+				rest = append(rest, x)
+				continue
+			}
+			progress = true
+			if err := c.apply(x, acc, schedule); err != nil {
+				x.status.State, x.status.Err = StateRejected, err
+				acc.nonce = x.ptx.Nonce
+				continue
+			}
+			included = append(included, x)
+		}
+		c.pending = rest
+	}
+	return c.seal(included, ts)
+}
+
+// Run seals a block every period until the context is done
+func (c *Chain) Run(ctx context.Context, period time.Duration) error {
+	t := time.NewTicker(period)
+	defer t.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-t.C:
+			if _, err := c.Seal(); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// ---
+
+func (c *Chain) account(addr mint.PublicKey) *account {
+	a, ok := c.accounts[addr]
+	if !ok {
+		a = &account{balances: fee.Balances{
+			mint.TokenGOLD: amount.New(),
+			mint.TokenMNT:  amount.New(),
+		}}
+		c.accounts[addr] = a
+	}
+	return a
+}
+
+// timestamp of the next block: the clock time, but not before the latest block
+func (c *Chain) timestamp() uint64 {
+	ts := mint.TimeToStamp(c.cfg.Clock.Now())
+	if len(c.headers) > 0 {
+		if last := c.headers[len(c.headers)-1].Timestamp; ts < last {
+			ts = last
+		}
+	}
+	return ts
+}
+
+// spend checks the policy and computes the amounts the sender spends (principal and fee)
+func (c *Chain) spend(t transaction.Transactioner, acc *account, schedule *fee.Schedule) (map[mint.Token]*big.Int, error) {
+	if c.cfg.Policy != nil {
+		if err := c.cfg.Policy.Authorize(t, acc.tags); err != nil {
+			return nil, err
+		}
+	}
+	est, err := schedule.EstimateSender(t, acc.balances, c.cfg.Policy != nil && c.cfg.Policy.IsFeeFree(acc.tags))
+	if err != nil {
+		return nil, err
+	}
+	spend := make(map[mint.Token]*big.Int)
+	for token, f := range est.Fee {
+		spend[token] = new(big.Int).Set(f.Value)
+	}
+	if t, ok := t.(*transaction.TransferAsset); ok {
+		if spend[t.Token] == nil {
+			spend[t.Token] = new(big.Int)
+		}
+		spend[t.Token].Add(spend[t.Token], t.Amount.Value)
+	}
+	for token, v := range spend {
+		avail := acc.balances[token]
+		if avail == nil {
+			avail = amount.New()
+		}
+		if avail.Value.Cmp(v) < 0 {
+			return nil, &wallet.InsufficientFundsError{Token: token, Required: amount.FromBig(v), Available: amount.FromAmount(avail)}
+		}
+	}
+	return spend, nil
+}
+
+// apply the transaction to the state
+func (c *Chain) apply(x *tx, acc *account, schedule *fee.Schedule) error {
+	spend, err := c.spend(x.tx, acc, schedule)
+	if err != nil {
+		return err
+	}
+	for token, v := range spend {
+		left := amount.FromAmount(acc.balances[token])
+		left.Value.Sub(left.Value, v)
+		acc.balances[token] = left
+	}
+
+	switch t := x.tx.(type) {
+	case *transaction.TransferAsset:
+		to := c.account(t.Address)
+		got := amount.New()
+		if b, ok := to.balances[t.Token]; ok {
+			got = amount.FromAmount(b)
+		}
+		got.Value.Add(got.Value, t.Amount.Value)
+		to.balances[t.Token] = got
+	case *transaction.SetWalletTag:
+		to := c.account(t.Address)
+		to.tags = append(removeTag(to.tags, t.Tag), t.Tag)
+	case *transaction.UnsetWalletTag:
+		to := c.account(t.Address)
+		to.tags = removeTag(to.tags, t.Tag)
+	}
+	acc.nonce = x.ptx.Nonce
+	return nil
+}
+
+// seal the transactions into a block signed by the validators
+func (c *Chain) seal(included []*tx, timestamp uint64) (*block.Header, error) {
+	h := &block.Header{
+		Version:           1,
+		Timestamp:         timestamp,
+		TransactionsCount: uint16(len(included)),
+		BlockID:           big.NewInt(int64(len(c.headers))),
+		Signers:           []block.Signer{},
+	}
+	if len(c.headers) > 0 {
+		h.PrevBlockDigest = c.headers[len(c.headers)-1].Digest
+	}
+	digests := make([]mint.Digest, len(included))
+	for i, x := range included {
+		digests[i] = x.ptx.Digest
+	}
+	h.MerkleRoot = merkleRoot(digests)
+	digest, err := h.ComputeDigest()
+	if err != nil {
+		return nil, err
+	}
+	h.Digest = digest
+	for _, v := range c.validators {
+		h.Signers = append(h.Signers, block.Signer{PublicKey: v.PublicKey(), Signature: v.Sign(digest[:])})
+	}
+	h.SignersCount = uint16(len(h.Signers))
+
+	data, err := h.Encode()
+	if err != nil {
+		return nil, err
+	}
+	for _, x := range included {
+		e, err := x.envelope.Encode()
+		if err != nil {
+			return nil, err
+		}
+		data = append(data, e...)
+		x.status.State, x.status.BlockID = StateIncluded, h.BlockID
+	}
+	c.blocks = append(c.blocks, data)
+	c.headers = append(c.headers, h)
+	return h, nil
+}
+
+func removeTag(tags []mint.WalletTag, tag mint.WalletTag) []mint.WalletTag {
+	ret := tags[:0]
+	for _, t := range tags {
+		if t != tag {
+			ret = append(ret, t)
+		}
+	}
+	return ret
+}
+
+// merkleRoot of the digests: a node is SHA3-256 of its children concatenated,
+// the last node of a level with odd count is paired with itself, a single digest is the root itself
+func merkleRoot(digests []mint.Digest) mint.Digest {
+	if len(digests) == 0 {
+		return mint.Digest{}
+	}
+	level := append([]mint.Digest(nil), digests...)
+	for len(level) > 1 {
+		if len(level)%2 != 0 {
+			level = append(level, level[len(level)-1])
+		}
+		next := level[:0]
+		for i := 0; i < len(level); i += 2 {
+			next = append(next, sha3.Sum256(bytes.Join([][]byte{level[i][:], level[i+1][:]}, nil)))
+		}
+		level = next
+	}
+	return level[0]
+}
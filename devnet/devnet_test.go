@@ -0,0 +1,360 @@
+package devnet
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"testing"
+	"time"
+
+	mint "github.com/void616/gm.mint"
+	"github.com/void616/gm.mint/amount"
+	"github.com/void616/gm.mint/block"
+	"github.com/void616/gm.mint/fee"
+	"github.com/void616/gm.mint/policy"
+	"github.com/void616/gm.mint/signer"
+	"github.com/void616/gm.mint/transaction"
+	"github.com/void616/gm.mint/wallet"
+	"golang.org/x/crypto/sha3"
+)
+
+func seal(t *testing.T, c *Chain, tx transaction.Transactioner, s *signer.Signer, nonce uint64) (transaction.TxID, error) {
+	t.Helper()
+	e, _, err := transaction.Seal(tx, s, nonce)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c.SubmitEnvelope(context.Background(), e)
+}
+
+func transfer(token mint.Token, to mint.PublicKey, a string) *transaction.TransferAsset {
+	return &transaction.TransferAsset{Token: token, Address: to, Amount: amount.MustFromString(a)}
+}
+
+func TestChain_Blocks(t *testing.T) {
+	v1, _ := signer.New()
+	v2, _ := signer.New()
+	sup, _ := signer.New()
+	user, _ := signer.New()
+	genesis := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	clock := NewManualClock(genesis)
+	c, err := New(Config{
+		Genesis: Genesis{Accounts: map[mint.PublicKey]Allocation{
+			sup.PublicKey():  {Tags: []mint.WalletTag{mint.WalletTagSupervisor}},
+			user.PublicKey(): {Balances: fee.Balances{mint.TokenGOLD: amount.MustFromString("1"), mint.TokenMNT: amount.MustFromString("100")}},
+		}},
+		Validators: []*signer.Signer{v1, v2},
+		Policy:     policy.Default(),
+		Clock:      clock,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// supervisor is fee-free
+	tagID, err := seal(t, c, &transaction.SetWalletTag{Address: user.PublicKey(), Tag: mint.WalletTagApproved}, sup, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// nonce 2 is applied after nonce 1 within the block
+	dataID, err := seal(t, c, &transaction.UserData{Data: []byte("hello")}, user, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := seal(t, c, transfer(mint.TokenMNT, mint.PublicKey{1}, "1"), user, 1); err != nil {
+		t.Fatal(err)
+	}
+	clock.Advance(time.Second)
+	h1, err := c.Seal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	clock.Set(genesis) // clock goes backwards
+	h2, err := c.Seal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if h2.Timestamp != h1.Timestamp || h1.Timestamp != mint.TimeToStamp(genesis.Add(time.Second)) {
+		t.Fatalf("timestamps %v, %v", h1.Timestamp, h2.Timestamp)
+	}
+
+	// state
+	acc, _ := c.Account(context.Background(), user.PublicKey())
+	mntFee := fee.Default().MntFee(amount.MustFromString("1"))
+	dataFee := fee.Default().UserDataFee(5)
+	left := new(big.Int).Sub(amount.MustFromString("99").Value, mntFee.Value)
+	left.Sub(left, dataFee.Value)
+	if acc.Nonce != 2 || acc.Balances[mint.TokenMNT].Value.Cmp(left) != 0 || len(acc.Tags) != 1 || acc.Tags[0] != mint.WalletTagApproved {
+		t.Fatalf("account %+v", acc)
+	}
+	if got := c.Balance(sup.PublicKey(), mint.TokenMNT); got.Value.Sign() != 0 {
+		t.Fatalf("supervisor balance %v", got)
+	}
+	if st, ok := c.Status(dataID); !ok || st.State != StateIncluded || st.BlockID.Int64() != 1 {
+		t.Fatalf("status %+v", st)
+	}
+
+	// blocks
+	var prev mint.Digest
+	for id := uint64(0); id < 3; id++ {
+		data, ok := c.Block(id)
+		if !ok {
+			t.Fatalf("block %v is not found", id)
+		}
+		var ids []transaction.TxID
+		err := block.ParseBytes(data, func(h *block.Header) error {
+			if h.BlockID.Uint64() != id || h.PrevBlockDigest != prev || h.SignersCount != 2 {
+				t.Fatalf("block %v header %+v", id, h)
+			}
+			for i, v := range []*signer.Signer{v1, v2} {
+				if h.Signers[i].PublicKey != v.PublicKey() || signer.Verify(v.PublicKey(), h.Digest[:], h.Signers[i].Signature) != nil {
+					t.Fatalf("block %v signer %v", id, i)
+				}
+			}
+			prev = h.Digest
+			return nil
+		}, block.Envelopes(func(_ *transaction.Envelope, _ transaction.Transactioner, ptx *transaction.ParsedTransaction, _ *block.Header) error {
+			ids = append(ids, ptx.ID())
+			return nil
+		}))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if id == 1 && (len(ids) != 3 || ids[0] != tagID || ids[2] != dataID) {
+			t.Fatalf("block 1 transactions %v", ids)
+		}
+	}
+	if prev != c.Latest().Digest {
+		t.Fatal("latest block mismatch")
+	}
+	if _, ok := c.Block(3); ok {
+		t.Fatal("block 3 is found")
+	}
+}
+
+func TestChain_Reject(t *testing.T) {
+	s, _ := signer.New()
+	c, err := New(Config{
+		Genesis: Genesis{Accounts: map[mint.PublicKey]Allocation{
+			s.PublicKey(): {Balances: fee.Balances{mint.TokenMNT: amount.MustFromString("1.5")}},
+		}},
+		Policy: policy.Default(),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	to := mint.PublicKey{1}
+
+	// submission
+	var ierr *wallet.InsufficientFundsError
+	if _, err := seal(t, c, transfer(mint.TokenMNT, to, "2"), s, 1); !errors.As(err, &ierr) {
+		t.Fatalf("insufficient funds: %v", err)
+	}
+	var derr *policy.DeniedError
+	if _, err := seal(t, c, transfer(mint.TokenGOLD, to, "0.1"), s, 1); !errors.As(err, &derr) {
+		t.Fatalf("GOLD transfer of untagged: %v", err)
+	}
+	e, _, _ := transaction.Seal(&transaction.UserData{Data: []byte{1}}, s, 1)
+	e.Data[len(e.Data)-1] ^= 1
+	if _, err := c.SubmitEnvelope(context.Background(), e); !errors.Is(err, ErrSignature) {
+		t.Fatalf("bad signature: %v", err)
+	}
+
+	// the second transfer fits the balance alone, but not after the first one
+	id1, err := seal(t, c, transfer(mint.TokenMNT, to, "1"), s, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if id, err := seal(t, c, transfer(mint.TokenMNT, to, "1"), s, 1); err != nil || id != id1 {
+		t.Fatalf("resubmission: %v", err)
+	}
+	if _, err := seal(t, c, transfer(mint.TokenMNT, to, "0.1"), s, 1); !errors.Is(err, ErrNoncePending) {
+		t.Fatalf("pending nonce: %v", err)
+	}
+	id2, err := seal(t, c, transfer(mint.TokenMNT, to, "0.47"), s, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	id3, err := seal(t, c, transfer(mint.TokenMNT, to, "0.1"), s, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.Seal(); err != nil {
+		t.Fatal(err)
+	}
+	if st, _ := c.Status(id1); st.State != StateIncluded {
+		t.Fatalf("status %+v", st)
+	}
+	if st, _ := c.Status(id2); st.State != StateRejected || !errors.As(st.Err, &ierr) {
+		t.Fatalf("status %+v", st)
+	}
+
+	// the rejected nonce is consumed, the following transaction isn't stalled
+	if st, _ := c.Status(id3); st.State != StateIncluded {
+		t.Fatalf("status %+v", st)
+	}
+	if acc, err := c.Account(context.Background(), s.PublicKey()); err != nil || acc.Nonce != 3 || acc.Balances[mint.TokenMNT].String() != amount.MustFromString("0.36").String() {
+		t.Fatalf("account %+v, %v", acc, err)
+	}
+	if _, err := seal(t, c, transfer(mint.TokenMNT, to, "0.1"), s, 2); !errors.Is(err, ErrNonceUsed) {
+		t.Fatalf("used nonce: %v", err)
+	}
+
+	// resubmission of the rejected transaction is validated again
+	if _, err := seal(t, c, transfer(mint.TokenMNT, to, "0.47"), s, 2); !errors.Is(err, ErrNonceUsed) {
+		t.Fatalf("rejected resubmission: %v", err)
+	}
+	if _, ok := c.Status(transaction.TxID{}); ok {
+		t.Fatal("unknown transaction is found")
+	}
+}
+
+// lossySubmitter loses the submissions while lose is set, or submits them and times out if deliver is set too
+type lossySubmitter struct {
+	*Chain
+	lose, deliver bool
+}
+
+func (s *lossySubmitter) Submit(ctx context.Context, e *transaction.Envelope) error {
+	if !s.lose {
+		return s.Chain.Submit(ctx, e)
+	}
+	if s.deliver {
+		s.Chain.Submit(ctx, e)
+	}
+	return context.DeadlineExceeded
+}
+
+func TestChain_WalletTimeout(t *testing.T) {
+	s, _ := signer.New()
+	c, err := New(Config{
+		Genesis: Genesis{Accounts: map[mint.PublicKey]Allocation{
+			s.PublicKey(): {Balances: fee.Balances{mint.TokenMNT: amount.MustFromString("10")}},
+		}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sub := &lossySubmitter{Chain: c}
+	w := &wallet.Wallet{Signer: s, Chain: c, Submitter: sub}
+	ctx := context.Background()
+	money := wallet.Money{Token: mint.TokenMNT, Amount: amount.MustFromString("1")}
+
+	// lost submission: the nonce is reused by the next sending
+	sub.lose = true
+	var uerr *wallet.UnknownOutcomeError
+	if r, err := w.Transfer(ctx, mint.PublicKey{1}, money); !errors.As(err, &uerr) || r.Nonce != 1 {
+		t.Fatalf("Transfer() = %+v, %v", r, err)
+	}
+	sub.lose = false
+	r1, err := w.Transfer(ctx, mint.PublicKey{1}, money)
+	if err != nil || r1.Nonce != 1 {
+		t.Fatalf("Transfer() = %+v, %v", r1, err)
+	}
+
+	// delivered, but timed out: the nonce is kept
+	sub.lose, sub.deliver = true, true
+	r2, err := w.Transfer(ctx, mint.PublicKey{2}, money)
+	if !errors.As(err, &uerr) || r2.Nonce != 2 {
+		t.Fatalf("Transfer() = %+v, %v", r2, err)
+	}
+	sub.lose = false
+	r3, err := w.Transfer(ctx, mint.PublicKey{3}, money)
+	if err != nil || r3.Nonce != 3 {
+		t.Fatalf("Transfer() = %+v, %v", r3, err)
+	}
+
+	if _, err := c.Seal(); err != nil {
+		t.Fatal(err)
+	}
+	for _, r := range []*wallet.Receipt{r1, r2, r3} {
+		if st, _ := c.Status(r.ID); st.State != StateIncluded {
+			t.Fatalf("nonce %v status %+v", r.Nonce, st)
+		}
+	}
+}
+
+func TestChain_Limits(t *testing.T) {
+	s, _ := signer.New()
+	cheap := fee.Default()
+	cheap.ActivationHeight, cheap.MntFixed = 2, amount.New()
+	schedules, err := fee.NewSchedules(cheap)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, err := New(Config{
+		Genesis: Genesis{Accounts: map[mint.PublicKey]Allocation{
+			s.PublicKey(): {Balances: fee.Balances{mint.TokenMNT: amount.MustFromString("10")}},
+		}},
+		Schedules:       schedules,
+		MaxTransactions: 1,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for n := uint64(1); n <= 2; n++ {
+		if _, err := seal(t, c, transfer(mint.TokenMNT, mint.PublicKey{1}, "1"), s, n); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// one transaction per block, the fee is free from block 2
+	for id := 1; id <= 2; id++ {
+		h, err := c.Seal()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if h.TransactionsCount != 1 {
+			t.Fatalf("block %v has %v transactions", id, h.TransactionsCount)
+		}
+	}
+	want := new(big.Int).Sub(amount.MustFromString("8").Value, fee.Default().MntFee(amount.MustFromString("1")).Value)
+	if got := c.Balance(s.PublicKey(), mint.TokenMNT); got.Value.Cmp(want) != 0 {
+		t.Fatalf("balance %v", got)
+	}
+}
+
+func TestChain_Run(t *testing.T) {
+	c, err := New(Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if err := c.Run(ctx, 5*time.Millisecond); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatal(err)
+	}
+	if c.Latest().BlockID.Int64() < 2 {
+		t.Fatalf("latest block %v", c.Latest().BlockID)
+	}
+}
+
+func TestNew(t *testing.T) {
+	_, err := New(Config{Genesis: Genesis{Accounts: map[mint.PublicKey]Allocation{
+		{}: {Balances: fee.Balances{mint.TokenMNT: amount.MustFromString("-1")}},
+	}}})
+	if err == nil {
+		t.Fatal("negative balance is accepted")
+	}
+}
+
+func TestMerkleRoot(t *testing.T) {
+	a, b, c := mint.Digest{1}, mint.Digest{2}, mint.Digest{3}
+	hash := func(l, r mint.Digest) mint.Digest {
+		return sha3.Sum256(append(l[:], r[:]...))
+	}
+	tests := []struct {
+		digests []mint.Digest
+		want    mint.Digest
+	}{
+		{nil, mint.Digest{}},
+		{[]mint.Digest{a}, a},
+		{[]mint.Digest{a, b}, hash(a, b)},
+		{[]mint.Digest{a, b, c}, hash(hash(a, b), hash(c, c))},
+	}
+	for _, tt := range tests {
+		if got := merkleRoot(tt.digests); got != tt.want {
+			t.Errorf("merkleRoot(%v) = %v, want %v", len(tt.digests), got, tt.want)
+		}
+	}
+}
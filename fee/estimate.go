@@ -0,0 +1,200 @@
+package fee
+
+import (
+	"fmt"
+	"math/big"
+	"strings"
+
+	mint "github.com/void616/gm.mint"
+	"github.com/void616/gm.mint/amount"
+	"github.com/void616/gm.mint/transaction"
+)
+
+// Balances of a sender per token
+type Balances map[mint.Token]*amount.Amount
+
+// Rule is a kind of fee calculation
+type Rule string
+
+const (
+	// RuleFree means a transaction is fee-free (see Schedule.Free)
+	RuleFree Rule = "free"
+	// RuleFreeSender means a sender is fee-free (see Schedule.EstimateSender)
+	RuleFreeSender Rule = "free_sender"
+	// RuleGoldTier means a GOLD fee is a percent of the principal (depending on sender's MNT balance)
+	RuleGoldTier Rule = "gold_tier"
+	// RuleMntFixed means a fixed MNT fee
+	RuleMntFixed Rule = "mnt_fixed"
+	// RuleUserDataPerByte means a MNT fee per byte of user data
+	RuleUserDataPerByte Rule = "user_data_per_byte"
+)
+
+// Estimation of a transaction fee
+type Estimation struct {
+	// Fee per token
+	Fee map[mint.Token]*amount.Amount
+	// Breakdown explains the fee
+	Breakdown []Item
+}
+
+// Item of a fee breakdown
+type Item struct {
+	// Token of the fee
+	Token mint.Token
+	// Fee amount
+	Fee *amount.Amount
+	// Rule applied
+	Rule Rule
+	// Tier index (RuleGoldTier)
+	Tier int
+	// Percent of the principal (RuleGoldTier)
+	Percent *big.Rat
+	// Rate per byte (RuleUserDataPerByte)
+	PerByte *amount.Amount
+	// Size of user data in bytes (RuleUserDataPerByte)
+	Size uint32
+	// MinApplied is true if the fee is clamped to the tier min fee
+	MinApplied bool
+	// MaxApplied is true if the fee is clamped to the tier max fee (cap)
+	MaxApplied bool
+	// Min fee of the tier
+	Min *amount.Amount
+	// Max fee of the tier
+	Max *amount.Amount
+}
+
+// String representation, like: fee 0.00002 GOLD (min fee applied)
+func (i Item) String() string {
+	var note string
+	switch i.Rule {
+	case RuleFree:
+		note = "fee-free"
+	case RuleFreeSender:
+		note = "fee-free sender"
+	case RuleGoldTier:
+		note = fmt.Sprintf("tier %v, %v%%", i.Tier, trimZeros(i.Percent.FloatString(amount.Precision)))
+		if i.MaxApplied {
+			note = "max fee applied"
+		}
+		if i.MinApplied {
+			note = "min fee applied"
+		}
+	case RuleMntFixed:
+		note = "fixed fee"
+	case RuleUserDataPerByte:
+		note = fmt.Sprintf("%v bytes at %v %v per byte", i.Size, trimZeros(i.PerByte.String()), i.Token)
+	}
+	return fmt.Sprintf("fee %v %v (%v)", trimZeros(i.Fee.String()), i.Token, note)
+}
+
+// String representation of the whole estimation
+func (e *Estimation) String() string {
+	s := make([]string, len(e.Breakdown))
+	for i, v := range e.Breakdown {
+		s[i] = v.String()
+	}
+	return strings.Join(s, "; ")
+}
+
+// Estimate a fee of the transaction with the default schedule. See Schedule.Estimate
+func Estimate(tx transaction.Transactioner, balances Balances) (*Estimation, error) {
+	return defaultSchedule.Estimate(tx, balances)
+}
+
+// Estimate a fee of the transaction sent from an address with specified `balances` (MNT balance is required for GOLD transfers)
+func (s *Schedule) Estimate(tx transaction.Transactioner, balances Balances) (*Estimation, error) {
+	return s.EstimateSender(tx, balances, false)
+}
+
+// EstimateSender estimates a fee of the transaction like Estimate, a `feeFree` sender (see policy.Policy.IsFeeFree) pays nothing
+func (s *Schedule) EstimateSender(tx transaction.Transactioner, balances Balances, feeFree bool) (*Estimation, error) {
+	var item Item
+	switch t := tx.(type) {
+	case *transaction.TransferAsset:
+		if t.Amount == nil {
+			return nil, fmt.Errorf("transfer amount is nil")
+		}
+		switch t.Token {
+		case mint.TokenGOLD:
+			mnt, ok := balances[mint.TokenMNT]
+			if !ok || mnt == nil {
+				return nil, fmt.Errorf("MNT balance is required to estimate GOLD fee")
+			}
+			item = s.goldItem(t.Amount, mnt)
+		case mint.TokenMNT:
+			item = Item{Token: mint.TokenMNT, Fee: s.MntFee(t.Amount), Rule: RuleMntFixed}
+		default:
+			return nil, fmt.Errorf("unknown token %v", t.Token)
+		}
+	case *transaction.UserData:
+		size, err := userDataSize(t)
+		if err != nil {
+			return nil, err
+		}
+		item = Item{Token: mint.TokenMNT, Fee: s.UserDataFee(size), Rule: RuleUserDataPerByte, PerByte: amount.FromAmount(s.UserDataPerByte), Size: size}
+	case *transaction.RegisterNode,
+		*transaction.UnregisterNode,
+		*transaction.SetWalletTag,
+		*transaction.UnsetWalletTag,
+		*transaction.DistributionFee:
+		item = Item{Token: mint.TokenMNT, Fee: amount.FromAmount(s.MntFixed), Rule: RuleMntFixed}
+	default:
+		return nil, fmt.Errorf("transaction %T is not supported", tx)
+	}
+
+	switch {
+	case s.free(tx.Code()):
+		item = Item{Token: item.Token, Fee: amount.New(), Rule: RuleFree}
+	case feeFree:
+		item = Item{Token: item.Token, Fee: amount.New(), Rule: RuleFreeSender}
+	}
+	e := &Estimation{
+		Fee: make(map[mint.Token]*amount.Amount),
+	}
+	e.add(item)
+	return e, nil
+}
+
+// goldItem calculates GOLD fee with explanation
+func (s *Schedule) goldItem(principalGOLD, balanceMNT *amount.Amount) Item {
+	tier, t := s.goldTier(balanceMNT)
+
+	r := t.rate()
+	f := new(big.Int).Mul(principalGOLD.Value, r.Num())
+	divRound(f, r.Denom())
+	min, max := t.clamp(f)
+
+	item := Item{
+		Token:      mint.TokenGOLD,
+		Fee:        amount.FromBig(f),
+		Rule:       RuleGoldTier,
+		Tier:       tier,
+		Percent:    new(big.Rat).Set(t.Percent),
+		MinApplied: min,
+		MaxApplied: max,
+	}
+	if t.Min != nil {
+		item.Min = amount.FromAmount(t.Min)
+	}
+	if t.Max != nil {
+		item.Max = amount.FromAmount(t.Max)
+	}
+	return item
+}
+
+func (e *Estimation) add(i Item) {
+	e.Breakdown = append(e.Breakdown, i)
+	if f, ok := e.Fee[i.Token]; ok {
+		f.Value.Add(f.Value, i.Fee.Value)
+		return
+	}
+	e.Fee[i.Token] = amount.FromAmount(i.Fee)
+}
+
+// trimZeros removes trailing zeros of a float string: 0.000020000000000000 => 0.00002
+func trimZeros(s string) string {
+	if !strings.Contains(s, ".") {
+		return s
+	}
+	return strings.TrimSuffix(strings.TrimRight(s, "0"), ".")
+}
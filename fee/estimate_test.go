@@ -0,0 +1,125 @@
+package fee
+
+import (
+	"testing"
+
+	mint "github.com/void616/gm.mint"
+	"github.com/void616/gm.mint/amount"
+	"github.com/void616/gm.mint/transaction"
+)
+
+func TestEstimate(t *testing.T) {
+	balances := Balances{
+		mint.TokenMNT:  amount.MustFromString("10000"),
+		mint.TokenGOLD: amount.MustFromString("100"),
+	}
+
+	tests := []struct {
+		name  string
+		tx    transaction.Transactioner
+		token mint.Token
+		fee   string
+		str   string
+	}{
+		{
+			"gold min",
+			&transaction.TransferAsset{Token: mint.TokenGOLD, Amount: amount.MustFromString("0.1")},
+			mint.TokenGOLD, "0.00002",
+			"fee 0.00002 GOLD (min fee applied)",
+		},
+		{
+			"gold max",
+			&transaction.TransferAsset{Token: mint.TokenGOLD, Amount: amount.MustFromString("1000")},
+			mint.TokenGOLD, "0.002",
+			"fee 0.002 GOLD (max fee applied)",
+		},
+		{
+			"gold tier",
+			&transaction.TransferAsset{Token: mint.TokenGOLD, Amount: amount.MustFromString("10")},
+			mint.TokenGOLD, "0.0003",
+			"fee 0.0003 GOLD (tier 3, 0.003%)",
+		},
+		{
+			"mnt",
+			&transaction.TransferAsset{Token: mint.TokenMNT, Amount: amount.MustFromString("10")},
+			mint.TokenMNT, "0.02",
+			"fee 0.02 MNT (fixed fee)",
+		},
+		{
+			"user data",
+			&transaction.UserData{Data: make([]byte, 10)},
+			mint.TokenMNT, "0.04",
+			"fee 0.04 MNT (10 bytes at 0.004 MNT per byte)",
+		},
+		{
+			"distribution fee",
+			&transaction.DistributionFee{AmountMNT: amount.New(), AmountGOLD: amount.New()},
+			mint.TokenMNT, "0",
+			"fee 0 MNT (fee-free)",
+		},
+		{
+			"set wallet tag",
+			&transaction.SetWalletTag{Tag: mint.WalletTagApproved},
+			mint.TokenMNT, "0",
+			"fee 0 MNT (fee-free)",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e, err := Estimate(tt.tx, balances)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := e.Fee[tt.token]; got == nil || got.Value.Cmp(amount.MustFromString(tt.fee).Value) != 0 {
+				t.Errorf("Estimate() fee = %v, want %v", got, tt.fee)
+			}
+			if got := e.String(); got != tt.str {
+				t.Errorf("Estimate() = %v, want %v", got, tt.str)
+			}
+		})
+	}
+
+	if _, err := Estimate(&transaction.TransferAsset{Token: mint.TokenGOLD, Amount: amount.FromInteger(1)}, nil); err == nil {
+		t.Error("Estimate() should fail without MNT balance")
+	}
+}
+
+func TestSchedule_EstimateSender(t *testing.T) {
+	balances := Balances{mint.TokenMNT: amount.MustFromString("10")}
+	gold := &transaction.TransferAsset{Token: mint.TokenGOLD, Amount: amount.MustFromString("10")}
+	tag := &transaction.SetWalletTag{Tag: mint.WalletTagApproved}
+
+	// fee-free sender
+	e, err := Default().EstimateSender(gold, balances, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := e.String(); got != "fee 0 GOLD (fee-free sender)" {
+		t.Errorf("EstimateSender() = %v", got)
+	}
+
+	// system transactions are charged if not free
+	s := Default()
+	s.Free = []transaction.Code{}
+	if e, err = s.Estimate(tag, balances); err != nil {
+		t.Fatal(err)
+	}
+	if got := e.String(); got != "fee 0.02 MNT (fixed fee)" {
+		t.Errorf("Estimate() = %v", got)
+	}
+	if e, err = s.EstimateSender(tag, balances, true); err != nil {
+		t.Fatal(err)
+	}
+	if got := e.String(); got != "fee 0 MNT (fee-free sender)" {
+		t.Errorf("EstimateSender() = %v", got)
+	}
+
+	// nil is the default
+	s.Free = nil
+	if e, err = s.Estimate(tag, balances); err != nil {
+		t.Fatal(err)
+	}
+	if got := e.String(); got != "fee 0 MNT (fee-free)" {
+		t.Errorf("Estimate() = %v", got)
+	}
+}
@@ -2,9 +2,11 @@ package fee
 
 import (
 	"math/big"
+	"math/rand"
 	"testing"
 
 	"github.com/void616/gm.mint/amount"
+	"github.com/void616/gm.mint/transaction"
 )
 
 func TestGoldFee(t *testing.T) {
@@ -125,3 +127,56 @@ func TestPurgeGold(t *testing.T) {
 		})
 	}
 }
+
+func TestPurgeGold_Rounding(t *testing.T) {
+	// the principal and its fee never exceed the balance: the former fee (balance - principal) was 1 wei more than
+	// the fee of the principal on some balances, or the sum exceeded the balance by 1 wei (the first one)
+	tests := []struct {
+		mnt       string
+		gold      string
+		principal string
+		fee       string
+	}{
+		{"0", "0.634650529656337694", "0.634016513143194499", "0.000634016513143194"},
+		{"10", "42.419564605741804439", "42.406842552975911666", "0.012722052765892773"},
+		{"10", "567.682738928794426815", "567.512485183239454979", "0.170253745554971836"},
+		{"1000", "67.797265449468625604", "67.795231592520849979", "0.002033856947775625"},
+		{"1000", "6.338738058914824302", "6.338547902477749970", "0.000190156437074332"},
+	}
+	for _, tt := range tests {
+		p, f, ok := PurgeGold(amount.MustFromString(tt.gold), amount.MustFromString(tt.mnt))
+		if !ok || p.String() != amount.MustFromString(tt.principal).String() || f.String() != amount.MustFromString(tt.fee).String() {
+			t.Errorf("PurgeGold(%v, %v) = %v, %v, %v, want %v, %v", tt.gold, tt.mnt, p, f, ok, tt.principal, tt.fee)
+		}
+	}
+
+	rnd := rand.New(rand.NewSource(1))
+	for _, mnt := range []string{"0", "10", "1000", "10000"} {
+		m := amount.MustFromString(mnt)
+		for i := 0; i < 1000; i++ {
+			g := amount.FromBig(new(big.Int).Rand(rnd, amount.MustFromString("1000").Value))
+			p, f, ok := PurgeGold(g, m)
+			if !ok {
+				continue
+			}
+			if f.String() != GoldFee(p, m).String() {
+				t.Fatalf("PurgeGold(%v, %v) fee = %v, want %v", g, mnt, f, GoldFee(p, m))
+			}
+			if sum := new(big.Int).Add(p.Value, f.Value); sum.Cmp(g.Value) > 0 {
+				t.Fatalf("PurgeGold(%v, %v) overspends: %v", g, mnt, amount.FromBig(sum))
+			}
+		}
+	}
+}
+
+func TestUserDataTxFee(t *testing.T) {
+	for _, size := range []uint32{0, 1, 64, 1000} {
+		got, err := UserDataTxFee(&transaction.UserData{Data: make([]byte, size)})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := UserDataFee(size); got.Value.Cmp(want.Value) != 0 {
+			t.Errorf("UserDataTxFee() = %v, want %v", got, want)
+		}
+	}
+}
@@ -0,0 +1,101 @@
+package fee
+
+import (
+	"math/big"
+
+	"github.com/void616/gm.mint/amount"
+)
+
+// GoldForNet estimates a transaction delivering exactly `netGOLD` GOLD to a recipient from a sender with `balanceMNT` MNT balance.
+// Returned `total` is what the sender spends (principal + fee)
+func GoldForNet(netGOLD *amount.Amount, balanceMNT *amount.Amount) (principalGOLD, feeGOLD, totalGOLD *amount.Amount) {
+	return defaultSchedule.GoldForNet(netGOLD, balanceMNT)
+}
+
+// GoldForTotal estimates a transaction spending exactly `totalGOLD` GOLD (principal + fee) from a sender with `balanceMNT` MNT balance.
+// See Schedule.GoldForTotal
+func GoldForTotal(totalGOLD *amount.Amount, balanceMNT *amount.Amount) (principalGOLD, feeGOLD *amount.Amount, exact, ok bool) {
+	return defaultSchedule.GoldForTotal(totalGOLD, balanceMNT)
+}
+
+// MntForNet estimates a transaction delivering exactly `netMNT` MNT to a recipient.
+// Returned `total` is what the sender spends (principal + fee)
+func MntForNet(netMNT *amount.Amount) (principalMNT, feeMNT, totalMNT *amount.Amount) {
+	return defaultSchedule.MntForNet(netMNT)
+}
+
+// MntForTotal estimates a transaction spending exactly `totalMNT` MNT (principal + fee).
+// See Schedule.MntForTotal
+func MntForTotal(totalMNT *amount.Amount) (principalMNT, feeMNT *amount.Amount, exact, ok bool) {
+	return defaultSchedule.MntForTotal(totalMNT)
+}
+
+// ---
+
+// GoldForNet estimates a transaction delivering exactly `netGOLD` GOLD to a recipient from a sender with `balanceMNT` MNT balance.
+// Returned `total` is what the sender spends (principal + fee)
+func (s *Schedule) GoldForNet(netGOLD *amount.Amount, balanceMNT *amount.Amount) (principalGOLD, feeGOLD, totalGOLD *amount.Amount) {
+	principalGOLD = amount.FromAmount(netGOLD)
+	feeGOLD = s.GoldFee(principalGOLD, balanceMNT)
+	totalGOLD = amount.FromBig(new(big.Int).Add(principalGOLD.Value, feeGOLD.Value))
+	return
+}
+
+// GoldForTotal estimates a transaction spending exactly `totalGOLD` GOLD (principal + fee) from a sender with `balanceMNT` MNT balance.
+// Returned principal is the largest one that satisfies `principal + GoldFee(principal) <= total`,
+// `exact` is true if the sum is equal to the total, otherwise the nearest feasible value is returned.
+// Returned `ok` is false if the transaction is impossible
+func (s *Schedule) GoldForTotal(totalGOLD *amount.Amount, balanceMNT *amount.Amount) (principalGOLD, feeGOLD *amount.Amount, exact, ok bool) {
+	_, t := s.goldTier(balanceMNT)
+	r := t.rate()
+	total := totalGOLD.Value
+
+	// principal + fee(principal) is strictly increasing, find the largest feasible principal in [0, total]
+	spend := func(p *big.Int) *big.Int {
+		f := new(big.Int).Mul(p, r.Num())
+		divRound(f, r.Denom())
+		t.clamp(f)
+		return f.Add(f, p)
+	}
+	lo, hi := big.NewInt(0), new(big.Int).Set(total)
+	if hi.Sign() <= 0 {
+		return
+	}
+	for lo.Cmp(hi) < 0 {
+		// mid = (lo + hi + 1) / 2
+		mid := new(big.Int).Add(lo, hi)
+		mid.Add(mid, big.NewInt(1)).Rsh(mid, 1)
+		if spend(mid).Cmp(total) <= 0 {
+			lo = mid
+		} else {
+			hi = mid.Sub(mid, big.NewInt(1))
+		}
+	}
+	if lo.Sign() <= 0 {
+		return
+	}
+
+	principalGOLD = amount.FromBig(lo)
+	feeGOLD = s.GoldFee(principalGOLD, balanceMNT)
+	exact = spend(lo).Cmp(total) == 0
+	ok = true
+	return
+}
+
+// MntForNet estimates a transaction delivering exactly `netMNT` MNT to a recipient.
+// Returned `total` is what the sender spends (principal + fee)
+func (s *Schedule) MntForNet(netMNT *amount.Amount) (principalMNT, feeMNT, totalMNT *amount.Amount) {
+	principalMNT = amount.FromAmount(netMNT)
+	feeMNT = s.MntFee(principalMNT)
+	totalMNT = amount.FromBig(new(big.Int).Add(principalMNT.Value, feeMNT.Value))
+	return
+}
+
+// MntForTotal estimates a transaction spending exactly `totalMNT` MNT (principal + fee).
+// MNT fee is fixed, so the result is always exact if the transaction is possible.
+// Returned `ok` is false if the transaction is impossible
+func (s *Schedule) MntForTotal(totalMNT *amount.Amount) (principalMNT, feeMNT *amount.Amount, exact, ok bool) {
+	principalMNT, feeMNT, ok = s.PurgeMnt(totalMNT)
+	exact = ok
+	return
+}
@@ -0,0 +1,77 @@
+package fee
+
+import (
+	"math/big"
+	"math/rand"
+	"testing"
+
+	"github.com/void616/gm.mint/amount"
+)
+
+func TestGoldForTotal(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+	balances := []string{"0", "10", "1000", "10000"}
+	totals := []string{
+		"0.00002", "0.000020000000000001", "0.00004", "0.020020", "0.02002",
+		"0.666686686686686686", "66.668666666666666666", "1000", "66.668666666666666667",
+	}
+	for i := 0; i < 200; i++ {
+		totals = append(totals, amount.FromBig(new(big.Int).Rand(rnd, amount.FromInteger(100000).Value)).String())
+	}
+
+	for _, mnt := range balances {
+		for _, total := range totals {
+			m := amount.MustFromString(mnt)
+			tot := amount.MustFromString(total)
+
+			p, f, exact, ok := GoldForTotal(tot, m)
+			if !ok {
+				if tot.Value.Cmp(goldMinFixed.Value) > 0 {
+					t.Fatalf("GoldForTotal(%v, %v) is not ok", total, mnt)
+				}
+				continue
+			}
+			if got := GoldFee(p, m); got.Value.Cmp(f.Value) != 0 {
+				t.Fatalf("GoldForTotal(%v, %v) fee = %v, want %v", total, mnt, f, got)
+			}
+			sum := new(big.Int).Add(p.Value, f.Value)
+			if c := sum.Cmp(tot.Value); c > 0 || (c == 0) != exact {
+				t.Fatalf("GoldForTotal(%v, %v) sum = %v, exact = %v", total, mnt, amount.FromBig(sum), exact)
+			}
+			// the next principal must overspend
+			next := amount.FromBig(new(big.Int).Add(p.Value, big.NewInt(1)))
+			nextSum := new(big.Int).Add(next.Value, GoldFee(next, m).Value)
+			if nextSum.Cmp(tot.Value) <= 0 {
+				t.Fatalf("GoldForTotal(%v, %v) principal %v is not the largest one", total, mnt, p)
+			}
+		}
+	}
+}
+
+func TestGoldForNet(t *testing.T) {
+	m := amount.MustFromString("10")
+	p, f, tot := GoldForNet(amount.MustFromString("100"), m)
+	if p.String() != "100.000000000000000000" || f.String() != "0.030000000000000000" || tot.String() != "100.030000000000000000" {
+		t.Fatalf("GoldForNet() = %v, %v, %v", p, f, tot)
+	}
+
+	// and back
+	p2, f2, exact, ok := GoldForTotal(tot, m)
+	if !ok || !exact || p2.Value.Cmp(p.Value) != 0 || f2.Value.Cmp(f.Value) != 0 {
+		t.Fatalf("GoldForTotal() = %v, %v, %v, %v", p2, f2, exact, ok)
+	}
+}
+
+func TestMntForTotal(t *testing.T) {
+	p, f, tot := MntForNet(amount.MustFromString("1"))
+	if tot.String() != "1.020000000000000000" {
+		t.Fatalf("MntForNet() = %v, %v, %v", p, f, tot)
+	}
+	p2, f2, exact, ok := MntForTotal(tot)
+	if !ok || !exact || p2.Value.Cmp(p.Value) != 0 || f2.Value.Cmp(f.Value) != 0 {
+		t.Fatalf("MntForTotal() = %v, %v, %v, %v", p2, f2, exact, ok)
+	}
+	if _, _, _, ok := MntForTotal(amount.MustFromString("0.02")); ok {
+		t.Fatal("MntForTotal() should not be ok")
+	}
+}
@@ -0,0 +1,314 @@
+package fee
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/big"
+	"sort"
+
+	"github.com/void616/gm.mint/amount"
+	"github.com/void616/gm.mint/transaction"
+)
+
+// Schedule is a set of fee rules effective from some block of the chain
+type Schedule struct {
+	// ActivationHeight is a block ID the schedule is effective from
+	ActivationHeight uint64 `json:"activation_height"`
+	// ActivationTime is a block timestamp the schedule is effective from
+	ActivationTime uint64 `json:"activation_time"`
+	// GoldTiers are GOLD fee tiers depending on sender's MNT balance
+	GoldTiers []Tier `json:"gold_tiers"`
+	// MntFixed is a fixed MNT fee
+	MntFixed *amount.Amount `json:"mnt_fixed"`
+	// UserDataPerByte is a MNT fee per byte of user data
+	UserDataPerByte *amount.Amount `json:"user_data_per_byte"`
+	// Free are codes of fee-free transactions, nil means the default ones (see Default).
+	// System transactions which are not free are charged MntFixed
+	Free []transaction.Code `json:"free"`
+}
+
+// Tier of GOLD fee
+type Tier struct {
+	// MinBalance is a sender's MNT balance the tier starts from
+	MinBalance *amount.Amount `json:"min_balance"`
+	// Percent of the principal, like "0.003" (0.003%)
+	Percent *big.Rat `json:"percent"`
+	// Min fee, optional
+	Min *amount.Amount `json:"min,omitempty"`
+	// Max fee, optional
+	Max *amount.Amount `json:"max,omitempty"`
+}
+
+// Schedules is a list of schedules sorted by activation
+type Schedules []*Schedule
+
+var defaultSchedule = &Schedule{
+	GoldTiers: []Tier{
+		// less than 10 MNT -> 0.1%
+		{MinBalance: amount.New(), Percent: big.NewRat(1, 10), Min: goldMinFixed},
+		// at least 10 MNT -> 0.03%
+		{MinBalance: mnt10, Percent: big.NewRat(3, 100), Min: goldMinFixed},
+		// at least 1 000 MNT -> 0.003%
+		{MinBalance: mnt1_000, Percent: big.NewRat(3, 1000), Min: goldMinFixed},
+		// at least 10 000 MNT -> 0.003%, max fee 0.002 GOLD
+		{MinBalance: mnt10_000, Percent: big.NewRat(3, 1000), Min: goldMinFixed, Max: goldMaxFixed},
+	},
+	MntFixed:        mntFixed,
+	UserDataPerByte: mntPerByte,
+	Free:            defaultFree,
+}
+
+// defaultFree are the system transactions: they don't move tokens, and the default policy lets only
+// the fee-free supervisor and owner send them, except the tags "approved" and "deposital" (see policy.Default)
+var defaultFree = []transaction.Code{
+	transaction.RegisterNodeTx,
+	transaction.UnregisterNodeTx,
+	transaction.SetWalletTagTx,
+	transaction.UnsetWalletTagTx,
+	transaction.DistributionFeeTx,
+}
+
+// Default schedule (a copy), effective from the genesis
+func Default() *Schedule {
+	return defaultSchedule.Copy()
+}
+
+// ParseSchedule parses a schedule from JSON and validates it
+func ParseSchedule(b []byte) (*Schedule, error) {
+	s := &Schedule{}
+	if err := json.Unmarshal(b, s); err != nil {
+		return nil, err
+	}
+	if err := s.Validate(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// ReadSchedules reads a JSON array of schedules, validates and sorts them by activation
+func ReadSchedules(r io.Reader) (Schedules, error) {
+	var list Schedules
+	if err := json.NewDecoder(r).Decode(&list); err != nil {
+		return nil, err
+	}
+	return NewSchedules(list...)
+}
+
+// NewSchedules validates the schedules and sorts them by activation
+func NewSchedules(list ...*Schedule) (Schedules, error) {
+	ret := make(Schedules, len(list))
+	copy(ret, list)
+	for i, s := range ret {
+		if s == nil {
+			return nil, fmt.Errorf("schedule at index %v is nil", i)
+		}
+		if err := s.Validate(); err != nil {
+			return nil, fmt.Errorf("schedule at index %v: %v", i, err)
+		}
+	}
+	sort.SliceStable(ret, func(i, j int) bool {
+		return ret[i].before(ret[j])
+	})
+	for i := 1; i < len(ret); i++ {
+		if !ret[i-1].before(ret[i]) {
+			return nil, fmt.Errorf("schedules have the same activation height %v and time %v", ret[i].ActivationHeight, ret[i].ActivationTime)
+		}
+	}
+	return ret, nil
+}
+
+// Resolve returns a schedule effective for a block with specified ID and timestamp.
+// The schedule is effective if both its activation height and time are reached; the latest one (by height, then by time) wins.
+// Default schedule is returned if there are no effective schedules. Returned schedule must not be modified
+func (ss Schedules) Resolve(blockID, timestamp uint64) *Schedule {
+	for i := len(ss) - 1; i >= 0; i-- {
+		if ss[i].ActivationHeight <= blockID && ss[i].ActivationTime <= timestamp {
+			return ss[i]
+		}
+	}
+	return defaultSchedule
+}
+
+// Validate the schedule
+func (s *Schedule) Validate() error {
+	if len(s.GoldTiers) == 0 {
+		return fmt.Errorf("gold tiers are empty")
+	}
+	for i, t := range s.GoldTiers {
+		switch {
+		case t.MinBalance == nil || t.MinBalance.IsNeg():
+			return fmt.Errorf("gold tier %v: min balance is nil or negative", i)
+		case i == 0 && t.MinBalance.Value.Sign() != 0:
+			return fmt.Errorf("gold tier %v: min balance must be zero", i)
+		case i > 0 && t.MinBalance.Value.Cmp(s.GoldTiers[i-1].MinBalance.Value) <= 0:
+			return fmt.Errorf("gold tier %v: min balance must be greater than previous one", i)
+		case t.Percent == nil || t.Percent.Sign() < 0:
+			return fmt.Errorf("gold tier %v: percent is nil or negative", i)
+		case t.Min != nil && t.Min.IsNeg():
+			return fmt.Errorf("gold tier %v: min fee is negative", i)
+		case t.Max != nil && t.Max.IsNeg():
+			return fmt.Errorf("gold tier %v: max fee is negative", i)
+		case t.Min != nil && t.Max != nil && t.Min.Value.Cmp(t.Max.Value) > 0:
+			return fmt.Errorf("gold tier %v: min fee is greater than max fee", i)
+		}
+	}
+	if s.MntFixed == nil || s.MntFixed.IsNeg() {
+		return fmt.Errorf("mnt fixed fee is nil or negative")
+	}
+	if s.UserDataPerByte == nil || s.UserDataPerByte.IsNeg() {
+		return fmt.Errorf("user data fee is nil or negative")
+	}
+	for _, c := range s.Free {
+		if !transaction.ValidCode(uint16(c)) {
+			return fmt.Errorf("free: unknown transaction code %v", uint16(c))
+		}
+	}
+	return nil
+}
+
+// Copy of the schedule
+func (s *Schedule) Copy() *Schedule {
+	cpy := func(a *amount.Amount) *amount.Amount {
+		if a == nil {
+			return nil
+		}
+		return amount.FromAmount(a)
+	}
+	ret := &Schedule{
+		ActivationHeight: s.ActivationHeight,
+		ActivationTime:   s.ActivationTime,
+		GoldTiers:        make([]Tier, len(s.GoldTiers)),
+		MntFixed:         cpy(s.MntFixed),
+		UserDataPerByte:  cpy(s.UserDataPerByte),
+	}
+	if s.Free != nil {
+		ret.Free = append([]transaction.Code{}, s.Free...)
+	}
+	for i, t := range s.GoldTiers {
+		ret.GoldTiers[i] = Tier{
+			MinBalance: cpy(t.MinBalance),
+			Min:        cpy(t.Min),
+			Max:        cpy(t.Max),
+		}
+		if t.Percent != nil {
+			ret.GoldTiers[i].Percent = new(big.Rat).Set(t.Percent)
+		}
+	}
+	return ret
+}
+
+func (s *Schedule) before(x *Schedule) bool {
+	if s.ActivationHeight != x.ActivationHeight {
+		return s.ActivationHeight < x.ActivationHeight
+	}
+	return s.ActivationTime < x.ActivationTime
+}
+
+// ---
+
+// GoldFee estimates fee for a transaction sending `principalGold` GOLD from a sender with `balanceMNT` MNT balance
+func (s *Schedule) GoldFee(principalGOLD *amount.Amount, balanceMNT *amount.Amount) (feeGOLD *amount.Amount) {
+	return s.goldItem(principalGOLD, balanceMNT).Fee
+}
+
+// MntFee estimates fee for a transaction sending `principalMNT` MNT
+func (s *Schedule) MntFee(principalMNT *amount.Amount) (feeMNT *amount.Amount) {
+	return amount.FromAmount(s.MntFixed)
+}
+
+// UserDataFee estimates fee (in MNT) for a user-data transaction with payload message length of `messageSize` bytes
+func (s *Schedule) UserDataFee(messageSize uint32) (feeMNT *amount.Amount) {
+	ret := big.NewInt(int64(messageSize))
+	ret.Mul(ret, s.UserDataPerByte.Value)
+	return amount.FromBig(ret)
+}
+
+// UserDataTxFee estimates fee (in MNT) for a user-data transaction, the billable message size is taken from the encoded transaction payload
+func (s *Schedule) UserDataTxFee(tx *transaction.UserData) (feeMNT *amount.Amount, err error) {
+	size, err := userDataSize(tx)
+	if err != nil {
+		return nil, err
+	}
+	return s.UserDataFee(size), nil
+}
+
+// PurgeGold estimates address clearing transaction (both principal and fee, in GOLD) from an sender with `balanceMNT` MNT balance.
+// The principal is the largest one with the principal and its fee (see GoldFee) within the balance (see GoldForTotal),
+// so a few wei could remain if no principal spends the balance exactly.
+// Returned `ok` is false if the transaction is impossible
+func (s *Schedule) PurgeGold(balanceGOLD *amount.Amount, balanceMNT *amount.Amount) (principalGOLD, feeGOLD *amount.Amount, ok bool) {
+	principalGOLD, feeGOLD, _, ok = s.GoldForTotal(balanceGOLD, balanceMNT)
+	return
+}
+
+// PurgeMnt estimates address clearing transaction (both principal and fee, in MNT).
+// Returned `ok` is false if the transaction is impossible
+func (s *Schedule) PurgeMnt(balanceMNT *amount.Amount) (principalMNT, feeMNT *amount.Amount, ok bool) {
+	m := new(big.Int).Set(balanceMNT.Value)
+
+	// min fee
+	if m.Cmp(s.MntFixed.Value) <= 0 {
+		return
+	}
+
+	principalMNT = amount.FromBig(new(big.Int).Sub(m, s.MntFixed.Value))
+	feeMNT = amount.FromBig(new(big.Int).Set(s.MntFixed.Value))
+	ok = true
+	return
+}
+
+// free returns true if transactions of the code are fee-free
+func (s *Schedule) free(code transaction.Code) bool {
+	list := s.Free
+	if list == nil {
+		list = defaultFree
+	}
+	for _, c := range list {
+		if c == code {
+			return true
+		}
+	}
+	return false
+}
+
+// goldTier returns a tier (and its index) for the MNT balance
+func (s *Schedule) goldTier(balanceMNT *amount.Amount) (int, *Tier) {
+	for i := len(s.GoldTiers) - 1; i > 0; i-- {
+		if balanceMNT.Value.Cmp(s.GoldTiers[i].MinBalance.Value) >= 0 {
+			return i, &s.GoldTiers[i]
+		}
+	}
+	return 0, &s.GoldTiers[0]
+}
+
+// rate is a fraction of the principal (percent / 100)
+func (t *Tier) rate() *big.Rat {
+	return new(big.Rat).Quo(t.Percent, big.NewRat(100, 1))
+}
+
+// clamp fee to min/max
+func (t *Tier) clamp(fee *big.Int) (min, max bool) {
+	if t.Max != nil && fee.Cmp(t.Max.Value) > 0 {
+		fee.Set(t.Max.Value)
+		max = true
+	}
+	if t.Min != nil && fee.Cmp(t.Min.Value) < 0 {
+		fee.Set(t.Min.Value)
+		min = true
+	}
+	return
+}
+
+// userDataSize is a billable size of the user data: encoded payload size excluding the framing of an empty message
+func userDataSize(tx *transaction.UserData) (uint32, error) {
+	size, err := transaction.PayloadSize(tx)
+	if err != nil {
+		return 0, err
+	}
+	frame, err := transaction.PayloadSize(&transaction.UserData{Data: []byte{}})
+	if err != nil {
+		return 0, err
+	}
+	return uint32(size - frame), nil
+}
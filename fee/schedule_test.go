@@ -0,0 +1,124 @@
+package fee
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/void616/gm.mint/amount"
+)
+
+func TestSchedule_DefaultJSON(t *testing.T) {
+	b, err := json.Marshal(Default())
+	if err != nil {
+		t.Fatal(err)
+	}
+	s, err := ParseSchedule(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, mnt := range []string{"0", "9.999999999999999999", "10", "999", "1000", "10000", "99999"} {
+		for _, gold := range []string{"0.000000000000000001", "1.23451851925925", "41150.2058024687", "75335.123456789"} {
+			m := amount.MustFromString(mnt)
+			g := amount.MustFromString(gold)
+			if got, want := s.GoldFee(g, m), GoldFee(g, m); got.Value.Cmp(want.Value) != 0 {
+				t.Errorf("GoldFee(%v, %v) = %v, want %v", gold, mnt, got, want)
+			}
+		}
+	}
+	if got, want := s.UserDataFee(100), UserDataFee(100); got.Value.Cmp(want.Value) != 0 {
+		t.Errorf("UserDataFee() = %v, want %v", got, want)
+	}
+	if got, want := s.MntFee(amount.FromInteger(1)), MntFee(amount.FromInteger(1)); got.Value.Cmp(want.Value) != 0 {
+		t.Errorf("MntFee() = %v, want %v", got, want)
+	}
+	if len(s.Free) != len(defaultFree) || !strings.Contains(string(b), `"free":["register_node",`) {
+		t.Errorf("Free = %v in %s", s.Free, b)
+	}
+}
+
+func TestSchedules_Resolve(t *testing.T) {
+	const doc = `[
+		{
+			"activation_height": 1000,
+			"activation_time": 0,
+			"gold_tiers": [{"min_balance": "0", "percent": "0.5", "min": "0.001"}],
+			"mnt_fixed": "0.1",
+			"user_data_per_byte": "0.01"
+		},
+		{
+			"activation_height": 1000,
+			"activation_time": 19527035308000000,
+			"gold_tiers": [
+				{"min_balance": "0", "percent": "0.2"},
+				{"min_balance": "100", "percent": "0.02", "max": "1"}
+			],
+			"mnt_fixed": "0.05",
+			"user_data_per_byte": "0.002"
+		}
+	]`
+
+	ss, err := ReadSchedules(strings.NewReader(doc))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name      string
+		height    uint64
+		timestamp uint64
+		mntFee    string
+	}{
+		{"default", 999, 19527035308000000, "0.02"},
+		{"by height", 1000, 0, "0.1"},
+		{"by height, time is not reached", 5000, 19527035307999999, "0.1"},
+		{"by height and time", 1000, 19527035308000000, "0.05"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := ss.Resolve(tt.height, tt.timestamp)
+			if got := s.MntFee(amount.FromInteger(1)); got.Value.Cmp(amount.MustFromString(tt.mntFee).Value) != 0 {
+				t.Errorf("Resolve() mnt fee = %v, want %v", got, tt.mntFee)
+			}
+		})
+	}
+
+	s := ss.Resolve(1000, 0)
+	if got := s.GoldFee(amount.FromInteger(1), amount.New()); got.String() != "0.005000000000000000" {
+		t.Errorf("GoldFee() = %v", got)
+	}
+	if got := s.GoldFee(amount.MustFromString("0.1"), amount.New()); got.String() != "0.001000000000000000" {
+		t.Errorf("GoldFee() min = %v", got)
+	}
+	s = ss.Resolve(1000, 19527035308000000)
+	if got := s.GoldFee(amount.FromInteger(10000), amount.FromInteger(100)); got.String() != "1.000000000000000000" {
+		t.Errorf("GoldFee() max = %v", got)
+	}
+}
+
+func TestSchedule_Validate(t *testing.T) {
+	tests := []struct {
+		name string
+		doc  string
+	}{
+		{"no tiers", `{"gold_tiers": [], "mnt_fixed": "0.02", "user_data_per_byte": "0.004"}`},
+		{"first tier", `{"gold_tiers": [{"min_balance": "1", "percent": "0.1"}], "mnt_fixed": "0.02", "user_data_per_byte": "0.004"}`},
+		{"tiers order", `{"gold_tiers": [{"min_balance": "0", "percent": "0.1"}, {"min_balance": "0", "percent": "0.1"}], "mnt_fixed": "0.02", "user_data_per_byte": "0.004"}`},
+		{"percent", `{"gold_tiers": [{"min_balance": "0"}], "mnt_fixed": "0.02", "user_data_per_byte": "0.004"}`},
+		{"min max", `{"gold_tiers": [{"min_balance": "0", "percent": "0.1", "min": "2", "max": "1"}], "mnt_fixed": "0.02", "user_data_per_byte": "0.004"}`},
+		{"mnt", `{"gold_tiers": [{"min_balance": "0", "percent": "0.1"}], "user_data_per_byte": "0.004"}`},
+		{"free", `{"gold_tiers": [{"min_balance": "0", "percent": "0.1"}], "mnt_fixed": "0.02", "user_data_per_byte": "0.004", "free": [99]}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := ParseSchedule([]byte(tt.doc)); err == nil {
+				t.Error("ParseSchedule() should fail")
+			}
+		})
+	}
+
+	if _, err := NewSchedules(Default(), Default()); err == nil {
+		t.Error("NewSchedules() should fail on the same activation")
+	}
+}
@@ -0,0 +1,328 @@
+package inspect
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"reflect"
+	"strings"
+	"text/tabwriter"
+	"time"
+	"unicode/utf8"
+
+	"github.com/mr-tron/base58/base58"
+	mint "github.com/void616/gm.mint"
+	"github.com/void616/gm.mint/amount"
+	"github.com/void616/gm.mint/block"
+	"github.com/void616/gm.mint/serializer"
+	"github.com/void616/gm.mint/transaction"
+)
+
+// Report is an annotated layout of inspected data
+type Report struct {
+	// Kind of the data: transaction code name (like "transfer_asset") or "block"
+	Kind string `json:"kind"`
+	// Size of the data in bytes
+	Size int `json:"size"`
+	// Digest of the transaction payload or the block header, if parsed
+	Digest string `json:"digest,omitempty"`
+	// Fields in order of appearance
+	Fields []Field `json:"fields"`
+	// Trailing is a count of unread bytes after the data
+	Trailing int `json:"trailing,omitempty"`
+	// Missing is a count of bytes missing at the end of the data (at least)
+	Missing int `json:"missing,omitempty"`
+	// Error of the parsing, if any
+	Error string `json:"error,omitempty"`
+}
+
+// Field of inspected data
+type Field struct {
+	// Offset of the field in the data
+	Offset int64 `json:"offset"`
+	// Length of the field in bytes
+	Length int64 `json:"length"`
+	// Name of the field, like "TransferAsset.Amount"
+	Name string `json:"name"`
+	// Raw bytes in hex
+	Raw string `json:"raw"`
+	// Value decoded
+	Value string `json:"value"`
+}
+
+// Encoding of the data to decode
+type Encoding string
+
+const (
+	// EncodingAuto detects the encoding: hex if prefixed with 0x, otherwise hex or base58 if the data is valid only in one of them
+	EncodingAuto Encoding = ""
+	// EncodingHex is hex, optionally prefixed with 0x
+	EncodingHex Encoding = "hex"
+	// EncodingBase58 is base58 with or without checksum (see mint.Pack58)
+	EncodingBase58 Encoding = "base58"
+)
+
+// Decode data in the encoding. Hex without 0x prefix could also be a valid base58, so such data fails with EncodingAuto
+func Decode(s string, enc Encoding) ([]byte, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return nil, fmt.Errorf("data is empty")
+	}
+	switch enc {
+	case EncodingHex:
+		return decodeHex(s)
+	case EncodingBase58:
+		return decodeBase58(s)
+	case EncodingAuto:
+		if strings.HasPrefix(s, "0x") || strings.HasPrefix(s, "0X") {
+			return decodeHex(s)
+		}
+		h, herr := decodeHex(s)
+		b, berr := decodeBase58(s)
+		switch {
+		case herr == nil && berr == nil:
+			return nil, fmt.Errorf("data is both valid hex and base58, specify the encoding or prefix hex with 0x")
+		case herr == nil:
+			return h, nil
+		case berr == nil:
+			return b, nil
+		}
+		return nil, fmt.Errorf("data is neither hex nor base58")
+	}
+	return nil, fmt.Errorf("unknown encoding `%v`", enc)
+}
+
+func decodeHex(s string) ([]byte, error) {
+	b, err := hex.DecodeString(strings.TrimPrefix(strings.TrimPrefix(s, "0x"), "0X"))
+	if err != nil {
+		return nil, fmt.Errorf("data is not hex: %v", err)
+	}
+	return b, nil
+}
+
+func decodeBase58(s string) ([]byte, error) {
+	if b, err := mint.Unpack58(s); err == nil {
+		return b, nil
+	}
+	b, err := base58.Decode(s)
+	if err != nil {
+		return nil, fmt.Errorf("data is not base58: %v", err)
+	}
+	return b, nil
+}
+
+// Transaction inspects transaction data (without a code prefix)
+func Transaction(code transaction.Code, data []byte) *Report {
+	r := &Report{Kind: code.String(), Size: len(data)}
+	tx, err := transaction.CodeToTransaction(code)
+	if err != nil {
+		r.Kind = fmt.Sprintf("unknown (%v)", uint16(code))
+		r.Error = err.Error()
+		r.Trailing = len(data)
+		return r
+	}
+
+	t := newTracer(data)
+	t.structs[""] = tx
+	ptx, err := transaction.ParseDeserializer(tx, t.des)
+	if err == nil {
+		r.Digest = ptx.Digest.String()
+	}
+	t.complete(r, err)
+	return r
+}
+
+// Block inspects block data: the header, signers and transactions
+func Block(data []byte) *Report {
+	r := &Report{Kind: "block", Size: len(data)}
+
+	t := newTracer(data)
+	err := block.ParseDeserializer(t.des, serializer.DefaultLimits, func(h *block.Header) error {
+		r.Digest = h.Digest.String()
+		return nil
+	}, func(code transaction.Code, d *serializer.Deserializer, h *block.Header) error {
+		tx, err := transaction.CodeToTransaction(code)
+		if err != nil {
+			return err
+		}
+		t.structs[d.LabelPrefix()] = tx
+		_, err = transaction.ParseDeserializer(tx, d)
+		return err
+	})
+	t.complete(r, err)
+	return r
+}
+
+// Text representation of the report, a table of fields
+func (r *Report) Text() string {
+	buf := &bytes.Buffer{}
+	r.WriteText(buf)
+	return buf.String()
+}
+
+// WriteText writes text representation of the report
+func (r *Report) WriteText(w io.Writer) error {
+	head := fmt.Sprintf("%v: %v bytes", r.Kind, r.Size)
+	if r.Digest != "" {
+		head += ", digest " + r.Digest
+	}
+	if _, err := fmt.Fprintln(w, head); err != nil {
+		return err
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "OFFSET\tLENGTH\tFIELD\tVALUE\tRAW")
+	for _, f := range r.Fields {
+		fmt.Fprintf(tw, "%v\t%v\t%v\t%v\t%v\n", f.Offset, f.Length, f.Name, f.Value, f.Raw)
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+
+	if r.Error != "" {
+		fmt.Fprintf(w, "error: %v\n", r.Error)
+	}
+	if r.Missing > 0 {
+		fmt.Fprintf(w, "missing: at least %v bytes\n", r.Missing)
+	}
+	if r.Trailing > 0 {
+		_, err := fmt.Fprintf(w, "trailing: %v bytes\n", r.Trailing)
+		return err
+	}
+	return nil
+}
+
+// JSON representation of the report
+func (r *Report) JSON() ([]byte, error) {
+	return json.MarshalIndent(r, "", "  ")
+}
+
+// ---
+
+// tracer collects fields read by a deserializer
+type tracer struct {
+	data   []byte
+	des    *serializer.Deserializer
+	traced []serializer.Traced
+	// parsed transactions by label prefix, to annotate values
+	structs map[string]transaction.Transactioner
+}
+
+func newTracer(data []byte) *tracer {
+	t := &tracer{
+		data:    data,
+		structs: make(map[string]transaction.Transactioner),
+	}
+	t.des = serializer.NewSliceDeserializer(data).Trace(func(f serializer.Traced) {
+		t.traced = append(t.traced, f)
+	})
+	return t
+}
+
+// complete fills the report with traced fields and the result of parsing
+func (t *tracer) complete(r *Report, err error) {
+	for _, f := range t.traced {
+		r.Fields = append(r.Fields, Field{
+			Offset: f.Offset,
+			Length: f.Length,
+			Name:   f.Field,
+			Raw:    hex.EncodeToString(t.data[f.Offset : f.Offset+f.Length]),
+			Value:  t.value(f),
+		})
+	}
+
+	if err == nil {
+		r.Trailing = len(t.data) - int(t.des.Offset())
+		return
+	}
+	r.Error = err.Error()
+	var serr *serializer.Error
+	if errors.As(err, &serr) && (errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF)) && serr.Expected > serr.Got {
+		r.Missing = serr.Expected - serr.Got
+	}
+}
+
+// value of the field as a string
+func (t *tracer) value(f serializer.Traced) string {
+	name := f.Field
+	if i := strings.LastIndexByte(name, '.'); i >= 0 {
+		name = name[i+1:]
+	}
+
+	// annotate with a transaction struct field
+	for prefix, tx := range t.structs {
+		if !strings.HasPrefix(f.Field, prefix) {
+			continue
+		}
+		rest := strings.TrimPrefix(strings.TrimPrefix(f.Field, prefix), ".")
+		if i := strings.IndexByte(rest, '.'); i < 0 || rest[i+1:] != name {
+			continue
+		}
+		sf := reflect.ValueOf(tx).Elem().FieldByName(name)
+		if !sf.IsValid() || !sf.CanInterface() {
+			continue
+		}
+		switch v := sf.Interface().(type) {
+		case mint.Token:
+			return fmt.Sprintf("%v (%v)", uint16(v), v)
+		case mint.WalletTag:
+			return fmt.Sprintf("%v (%v)", uint8(v), v)
+		}
+	}
+
+	switch v := f.Value.(type) {
+	case byte:
+		if name == "Signed" {
+			if v == 0 {
+				return "0 (unsigned, followed by payload digest)"
+			}
+			return fmt.Sprintf("%v (signed, followed by signature)", v)
+		}
+		return fmt.Sprint(v)
+	case uint16:
+		if name == "Code" {
+			return fmt.Sprintf("%v (%v)", v, transaction.Code(v))
+		}
+		return fmt.Sprint(v)
+	case uint64:
+		if name == "Timestamp" {
+			return fmt.Sprintf("%v (%v)", v, mint.StampToTime(v).Format(time.RFC3339Nano))
+		}
+		return fmt.Sprint(v)
+	case uint32, *big.Int:
+		return fmt.Sprint(v)
+	case string:
+		return fmt.Sprintf("%q", v)
+	case []byte:
+		if len(v) > 0 && utf8.Valid(v) && isPrintable(string(v)) {
+			return fmt.Sprintf("%q", v)
+		}
+		return fmt.Sprintf("%v bytes", len(v))
+	case *amount.Amount:
+		return trimZeros(v.String())
+	case fmt.Stringer:
+		return v.String()
+	}
+	return fmt.Sprint(f.Value)
+}
+
+func isPrintable(s string) bool {
+	for _, r := range s {
+		if r < 0x20 || r == 0x7F || r == utf8.RuneError {
+			return false
+		}
+	}
+	return true
+}
+
+// trimZeros removes trailing zeros of a float string: 0.000020000000000000 => 0.00002
+func trimZeros(s string) string {
+	if !strings.Contains(s, ".") {
+		return s
+	}
+	return strings.TrimSuffix(strings.TrimRight(s, "0"), ".")
+}
@@ -0,0 +1,203 @@
+package inspect
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/mr-tron/base58/base58"
+	mint "github.com/void616/gm.mint"
+	"github.com/void616/gm.mint/amount"
+	"github.com/void616/gm.mint/serializer"
+	"github.com/void616/gm.mint/signer"
+	"github.com/void616/gm.mint/transaction"
+)
+
+var testAddress = mint.PublicKey{1, 2, 3}
+
+func testTransfer(t *testing.T) (*signer.Signer, []byte) {
+	s, _ := signer.New()
+	signed, err := (&transaction.TransferAsset{
+		Address: testAddress,
+		Token:   mint.TokenGOLD,
+		Amount:  amount.MustFromString("1.666"),
+	}).Sign(s, 42)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s, signed.Data
+}
+
+func TestTransaction(t *testing.T) {
+	s, data := testTransfer(t)
+
+	r := Transaction(transaction.TransferAssetTx, data)
+	if r.Error != "" || r.Trailing != 0 || r.Missing != 0 || r.Size != len(data) {
+		t.Fatalf("Transaction() = %+v", r)
+	}
+
+	want := []struct {
+		name   string
+		offset int64
+		length int64
+		value  string
+	}{
+		{"TransferAsset.Nonce", 0, 8, "42"},
+		{"TransferAsset.Token", 8, 2, "1 (GOLD)"},
+		{"TransferAsset.From", 10, 32, s.PublicKey().String()},
+		{"TransferAsset.Address", 42, 32, testAddress.String()},
+		{"TransferAsset.Amount", 74, 16, "1.666"},
+		{"TransferAsset.Signed", 90, 1, "1 (signed, followed by signature)"},
+		{"TransferAsset.Signature", 91, 64, ""},
+	}
+	if len(r.Fields) != len(want) {
+		t.Fatalf("Transaction() fields = %+v", r.Fields)
+	}
+	for i, w := range want {
+		f := r.Fields[i]
+		if f.Name != w.name || f.Offset != w.offset || f.Length != w.length || (w.value != "" && f.Value != w.value) {
+			t.Errorf("Transaction() field %v = %+v, want %+v", i, f, w)
+		}
+		if f.Raw != hex.EncodeToString(data[f.Offset:f.Offset+f.Length]) {
+			t.Errorf("Transaction() field %v raw = %v", i, f.Raw)
+		}
+	}
+
+	// trailing and missing bytes
+	r = Transaction(transaction.TransferAssetTx, append(append([]byte{}, data...), 1, 2, 3))
+	if r.Error != "" || r.Trailing != 3 {
+		t.Fatalf("Transaction() trailing = %+v", r)
+	}
+	r = Transaction(transaction.TransferAssetTx, data[:80])
+	if r.Missing != 10 || len(r.Fields) != 4 || !strings.Contains(r.Error, "TransferAsset.Amount at offset 74") {
+		t.Fatalf("Transaction() missing = %+v", r)
+	}
+	if txt := r.Text(); !strings.Contains(txt, "missing: at least 10 bytes") {
+		t.Fatal(txt)
+	}
+}
+
+func TestTransaction_Unsigned(t *testing.T) {
+	u, err := transaction.Unsigned(&transaction.UserData{Data: []byte("hello")}, mint.PublicKey{}, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := Transaction(transaction.UserDataTx, u.Data)
+	if r.Error != "" || r.Digest != u.Digest.String() {
+		t.Fatalf("Transaction() = %+v", r)
+	}
+	names := []string{}
+	for _, f := range r.Fields {
+		names = append(names, f.Name+"="+f.Value)
+	}
+	want := "UserData.Nonce=1 UserData.From=" + mint.PublicKey{}.String() + ` UserData.DataSize=5 UserData.Data="hello" UserData.Signed=0 (unsigned, followed by payload digest) UserData.Digest=` + u.Digest.String()
+	if got := strings.Join(names, " "); got != want {
+		t.Fatalf("Transaction() fields = %v, want %v", got, want)
+	}
+}
+
+func TestBlock(t *testing.T) {
+	s, tx := testTransfer(t)
+
+	h := &blockHeader{signers: 1}
+	ser := serializer.NewSerializer()
+	ser.PutBytes(h.encode(t, s))
+	ser.PutUint16(uint16(transaction.TransferAssetTx))
+	ser.PutBytes(tx)
+	data, _ := ser.Data()
+
+	r := Block(data)
+	if r.Error != "" || r.Trailing != 0 || r.Digest == "" {
+		t.Fatalf("Block() = %+v", r)
+	}
+	names := map[string]string{}
+	for _, f := range r.Fields {
+		names[f.Name] = f.Value
+	}
+	for name, value := range map[string]string{
+		"Header.TransactionsCount":                "1",
+		"Header.SignersCount":                     "1",
+		"Transactions[0].Code":                    "10 (transfer_asset)",
+		"Transactions[0].TransferAsset.Token":     "1 (GOLD)",
+		"Transactions[0].TransferAsset.Amount":    "1.666",
+		"Header.Signers[0].PublicKey":             s.PublicKey().String(),
+		"Transactions[0].TransferAsset.Signature": "",
+	} {
+		got, ok := names[name]
+		if !ok || (value != "" && got != value) {
+			t.Errorf("Block() field %v = %q, want %q", name, got, value)
+		}
+	}
+
+	// JSON
+	b, err := r.JSON()
+	if err != nil {
+		t.Fatal(err)
+	}
+	r2 := &Report{}
+	if err := json.Unmarshal(b, r2); err != nil || len(r2.Fields) != len(r.Fields) || r2.Digest != r.Digest {
+		t.Fatalf("JSON() = %s", b)
+	}
+}
+
+func TestDecode(t *testing.T) {
+	data := []byte{0xDE, 0xAD, 0xBE, 0xEF, 0x00}
+	tests := []struct {
+		s   string
+		enc Encoding
+	}{
+		{"0xdeadbeef00", EncodingAuto},
+		{" 0XDEADBEEF00\n", EncodingAuto},
+		{"deadbeef00", EncodingAuto},
+		{"deadbeef00", EncodingHex},
+		{mint.Pack58(data), EncodingAuto},
+		{mint.Pack58(data), EncodingBase58},
+		{base58.Encode(data), EncodingBase58},
+	}
+	for _, tt := range tests {
+		b, err := Decode(tt.s, tt.enc)
+		if err != nil || hex.EncodeToString(b) != "deadbeef00" {
+			t.Errorf("Decode(%q, %q) = %x, %v", tt.s, tt.enc, b, err)
+		}
+	}
+	// "deadbeef" is also valid base58
+	for _, enc := range []Encoding{EncodingAuto, EncodingBase58, "base64"} {
+		if b, err := Decode("deadbeef", enc); enc == EncodingBase58 {
+			if err != nil || hex.EncodeToString(b) == "deadbeef" {
+				t.Errorf("Decode(deadbeef, %q) = %x, %v", enc, b, err)
+			}
+		} else if err == nil {
+			t.Errorf("Decode(deadbeef, %q) should fail", enc)
+		}
+	}
+	if _, err := Decode("not data!", EncodingAuto); err == nil {
+		t.Error("Decode() should fail")
+	}
+}
+
+// blockHeader makes a block header
+type blockHeader struct {
+	signers int
+}
+
+func (h *blockHeader) encode(t *testing.T, s *signer.Signer) []byte {
+	ser := serializer.NewSerializer()
+	ser.PutUint16(1)                            // version
+	ser.PutBytes(make([]byte, mint.DigestSize)) // previous block digest
+	ser.PutUint16(0)                            // consensus round
+	ser.PutBytes(make([]byte, mint.DigestSize)) // merkle root
+	ser.PutUint64(19527035308000000)            // time
+	ser.PutUint16(1)                            // transactions
+	ser.PutBytes(make([]byte, 32))              // block
+	ser.PutUint16(uint16(h.signers))            // signers
+	for i := 0; i < h.signers; i++ {
+		ser.PutPublicKey(s.PublicKey())
+		ser.PutBytes(make([]byte, mint.SignatureSize))
+	}
+	b, err := ser.Data()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
@@ -0,0 +1,382 @@
+package mempool
+
+import (
+	"container/list"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	mint "github.com/void616/gm.mint"
+	"github.com/void616/gm.mint/nonce"
+	"github.com/void616/gm.mint/signer"
+	"github.com/void616/gm.mint/transaction"
+)
+
+// Errors of Add
+var (
+	// ErrDuplicate means the transaction is already in the pool
+	ErrDuplicate = errors.New("transaction is already in the pool")
+	// ErrUnsigned means the transaction has no signature
+	ErrUnsigned = errors.New("transaction is not signed")
+	// ErrSignature means the signature is invalid
+	ErrSignature = errors.New("transaction signature is invalid")
+	// ErrStale means the nonce is already included into the chain (see Sync)
+	ErrStale = errors.New("transaction nonce is already used")
+	// ErrFull means the pool is full and the transaction is evicted immediately
+	ErrFull = errors.New("pool is full")
+)
+
+// DefaultMaxIdle is a default count of idle senders to remember
+const DefaultMaxIdle = 10000
+
+// Config of the pool, zero values mean no limit unless noted
+type Config struct {
+	// MaxCount of the transactions
+	MaxCount int
+	// MaxBytes of the transactions data
+	MaxBytes int64
+	// MaxAge of a transaction in the pool
+	MaxAge time.Duration
+	// MaxIdle is a count of senders without transactions in the pool whose last included nonce (see Sync) is remembered,
+	// the least recently synced are forgotten first. Zero means DefaultMaxIdle
+	MaxIdle int
+}
+
+// Entry is a transaction in the pool
+type Entry struct {
+	ID       transaction.TxID
+	Envelope *transaction.Envelope
+	Tx       transaction.Transactioner
+	From     mint.PublicKey
+	Nonce    uint64
+	Added    time.Time
+}
+
+// Pool is a concurrency-safe queue of signed transactions before submission, ordered by nonce per sender
+type Pool struct {
+	mu     sync.Mutex
+	cfg    Config
+	now    func() time.Time
+	byID   map[transaction.TxID]*Entry
+	queues map[mint.PublicKey]*queue
+	size   int64
+	// last included nonces of senders without transactions, the most recent first
+	idle     *list.List
+	idleByPK map[mint.PublicKey]*list.Element
+}
+
+// queue of a sender, non-empty
+type queue struct {
+	// entries sorted by nonce
+	entries []*Entry
+	// last nonce included into the chain, if known
+	last  uint64
+	known bool
+}
+
+// idleSender is a sender without transactions in the pool
+type idleSender struct {
+	from mint.PublicKey
+	last uint64
+}
+
+// New pool
+func New(cfg Config) *Pool {
+	if cfg.MaxIdle <= 0 {
+		cfg.MaxIdle = DefaultMaxIdle
+	}
+	return &Pool{
+		cfg:      cfg,
+		now:      time.Now,
+		byID:     make(map[transaction.TxID]*Entry),
+		queues:   make(map[mint.PublicKey]*queue),
+		idle:     list.New(),
+		idleByPK: make(map[mint.PublicKey]*list.Element),
+	}
+}
+
+// AddSigned adds a signed transaction of the code (see Add)
+func (p *Pool) AddSigned(code transaction.Code, tx *transaction.SignedTransaction) (*Entry, error) {
+	return p.Add(&transaction.Envelope{Code: code, Data: tx.Data})
+}
+
+// Add parses and verifies the transaction and puts it into the pool.
+// A pending transaction of the same sender and nonce is replaced. Expired transactions are evicted, then the pool is shrunk to the limits
+func (p *Pool) Add(e *transaction.Envelope) (*Entry, error) {
+	tx, ptx, err := e.Open()
+	if err != nil {
+		return nil, err
+	}
+	if !ptx.Signed {
+		return nil, ErrUnsigned
+	}
+	if err := signer.Verify(ptx.From, ptx.Digest[:], ptx.Signature); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrSignature, err)
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	id := ptx.ID()
+	if _, ok := p.byID[id]; ok {
+		return nil, ErrDuplicate
+	}
+	if last, ok := p.last(ptx.From); ok && ptx.Nonce <= last {
+		return nil, ErrStale
+	}
+
+	entry := &Entry{
+		ID:       id,
+		Envelope: &transaction.Envelope{Code: e.Code, Data: append([]byte(nil), e.Data...)},
+		Tx:       tx,
+		From:     ptx.From,
+		Nonce:    ptx.Nonce,
+		Added:    p.now(),
+	}
+	var old *Entry
+	if q, ok := p.queues[entry.From]; ok {
+		old = q.find(entry.Nonce)
+	}
+	if old != nil {
+		p.remove(old)
+	}
+	p.insert(entry)
+
+	p.evict()
+	if _, ok := p.byID[id]; !ok {
+		// the replaced transaction fitted the limits before, keep it unless expired
+		if old != nil && (p.cfg.MaxAge <= 0 || !old.Added.Before(p.now().Add(-p.cfg.MaxAge))) {
+			p.insert(old)
+		}
+		return nil, ErrFull
+	}
+	return entry, nil
+}
+
+// Get a transaction by ID
+func (p *Pool) Get(id transaction.TxID) (*Entry, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	e, ok := p.byID[id]
+	return e, ok
+}
+
+// Remove a transaction by ID (like a dropped one), returns false if it's not found
+func (p *Pool) Remove(id transaction.TxID) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	e, ok := p.byID[id]
+	if ok {
+		p.remove(e)
+	}
+	return ok
+}
+
+// Sync sets the last nonce of the sender included into the chain, the transactions up to the nonce are removed.
+// The nonce of a sender without transactions is remembered for up to Config.MaxIdle senders
+func (p *Pool) Sync(from mint.PublicKey, last uint64) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	q, ok := p.queues[from]
+	if !ok {
+		p.setIdle(from, last)
+		return
+	}
+	q.last, q.known = last, true
+	for len(q.entries) > 0 && q.entries[0].Nonce <= last {
+		p.remove(q.entries[0])
+	}
+}
+
+// Evict expired transactions and shrink the pool to the limits
+func (p *Pool) Evict() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.evict()
+}
+
+// Ready returns the transactions to submit: a run of consecutive nonces of each sender,
+// starting after the last included nonce (see Sync) or the lowest pending nonce if the chain state is unknown.
+// Senders are ordered by the age of their first transaction
+func (p *Pool) Ready() []*Entry {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	var runs [][]*Entry
+	for _, q := range p.queues {
+		if q.known && q.entries[0].Nonce != q.last+1 {
+			continue
+		}
+		i := 1
+		for ; i < len(q.entries) && q.entries[i].Nonce == q.entries[i-1].Nonce+1; i++ {
+		}
+		runs = append(runs, q.entries[:i:i])
+	}
+	sort.Slice(runs, func(i, j int) bool {
+		if !runs[i][0].Added.Equal(runs[j][0].Added) {
+			return runs[i][0].Added.Before(runs[j][0].Added)
+		}
+		return runs[i][0].From.String() < runs[j][0].From.String()
+	})
+	var ret []*Entry
+	for _, r := range runs {
+		ret = append(ret, r...)
+	}
+	return ret
+}
+
+// Gaps returns missing nonces stalling pending transactions
+func (p *Pool) Gaps() []nonce.Gap {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	var ret []nonce.Gap
+	for from, q := range p.queues {
+		expect := q.entries[0].Nonce
+		if q.known {
+			expect = q.last + 1
+		}
+		for i, e := range q.entries {
+			for ; expect < e.Nonce; expect++ {
+				ret = append(ret, nonce.Gap{Address: from, Nonce: expect, Stalled: len(q.entries) - i})
+			}
+			expect = e.Nonce + 1
+		}
+	}
+	sort.Slice(ret, func(i, j int) bool {
+		if ret[i].Address != ret[j].Address {
+			return ret[i].Address.String() < ret[j].Address.String()
+		}
+		return ret[i].Nonce < ret[j].Nonce
+	})
+	return ret
+}
+
+// Len is a count of the transactions
+func (p *Pool) Len() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return len(p.byID)
+}
+
+// Size is a total size of the transactions data
+func (p *Pool) Size() int64 {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.size
+}
+
+// ---
+
+// queue of the sender, a new one takes the remembered last nonce of the idle sender
+func (p *Pool) queue(from mint.PublicKey) *queue {
+	q, ok := p.queues[from]
+	if !ok {
+		q = &queue{}
+		if el, ok := p.idleByPK[from]; ok {
+			q.last, q.known = el.Value.(*idleSender).last, true
+			p.idle.Remove(el)
+			delete(p.idleByPK, from)
+		}
+		p.queues[from] = q
+	}
+	return q
+}
+
+// last included nonce of the sender, if known
+func (p *Pool) last(from mint.PublicKey) (uint64, bool) {
+	if q, ok := p.queues[from]; ok {
+		return q.last, q.known
+	}
+	if el, ok := p.idleByPK[from]; ok {
+		return el.Value.(*idleSender).last, true
+	}
+	return 0, false
+}
+
+// setIdle remembers the last nonce of the sender without transactions, forgetting the least recent senders over the limit
+func (p *Pool) setIdle(from mint.PublicKey, last uint64) {
+	if el, ok := p.idleByPK[from]; ok {
+		el.Value.(*idleSender).last = last
+		p.idle.MoveToFront(el)
+		return
+	}
+	p.idleByPK[from] = p.idle.PushFront(&idleSender{from: from, last: last})
+	for p.idle.Len() > p.cfg.MaxIdle {
+		el := p.idle.Back()
+		delete(p.idleByPK, el.Value.(*idleSender).from)
+		p.idle.Remove(el)
+	}
+}
+
+func (p *Pool) insert(e *Entry) {
+	p.queue(e.From).insert(e)
+	p.byID[e.ID] = e
+	p.size += int64(len(e.Envelope.Data))
+}
+
+func (p *Pool) remove(e *Entry) {
+	delete(p.byID, e.ID)
+	p.size -= int64(len(e.Envelope.Data))
+	q := p.queues[e.From]
+	q.delete(e)
+	if len(q.entries) == 0 {
+		delete(p.queues, e.From)
+		if q.known {
+			p.setIdle(e.From, q.last)
+		}
+	}
+}
+
+// evict removes expired transactions, then the newest tails of the queues while the pool exceeds the limits.
+// Removing a tail doesn't stall other transactions of the sender
+func (p *Pool) evict() {
+	if p.cfg.MaxAge > 0 {
+		deadline := p.now().Add(-p.cfg.MaxAge)
+		for _, e := range p.byID {
+			if e.Added.Before(deadline) {
+				p.remove(e)
+			}
+		}
+	}
+	for p.cfg.MaxCount > 0 && len(p.byID) > p.cfg.MaxCount || p.cfg.MaxBytes > 0 && p.size > p.cfg.MaxBytes {
+		var victim *Entry
+		for _, q := range p.queues {
+			tail := q.entries[len(q.entries)-1]
+			if victim == nil || tail.Added.After(victim.Added) || tail.Added.Equal(victim.Added) && tail.Nonce > victim.Nonce {
+				victim = tail
+			}
+		}
+		if victim == nil {
+			return
+		}
+		p.remove(victim)
+	}
+}
+
+func (q *queue) search(n uint64) int {
+	return sort.Search(len(q.entries), func(i int) bool { return q.entries[i].Nonce >= n })
+}
+
+func (q *queue) find(n uint64) *Entry {
+	if i := q.search(n); i < len(q.entries) && q.entries[i].Nonce == n {
+		return q.entries[i]
+	}
+	return nil
+}
+
+func (q *queue) insert(e *Entry) {
+	i := q.search(e.Nonce)
+	q.entries = append(q.entries, nil)
+	copy(q.entries[i+1:], q.entries[i:])
+	q.entries[i] = e
+}
+
+func (q *queue) delete(e *Entry) {
+	if i := q.search(e.Nonce); i < len(q.entries) && q.entries[i] == e {
+		q.entries = append(q.entries[:i], q.entries[i+1:]...)
+	}
+}
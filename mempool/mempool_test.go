@@ -0,0 +1,252 @@
+package mempool
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	mint "github.com/void616/gm.mint"
+	"github.com/void616/gm.mint/amount"
+	"github.com/void616/gm.mint/nonce"
+	"github.com/void616/gm.mint/signer"
+	"github.com/void616/gm.mint/transaction"
+)
+
+func testTx(t testing.TB, s *signer.Signer, n uint64, amt string) *transaction.Envelope {
+	e, _, err := transaction.Seal(&transaction.TransferAsset{
+		Token:   mint.TokenMNT,
+		Address: mint.PublicKey{1},
+		Amount:  amount.MustFromString(amt),
+	}, s, n)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return e
+}
+
+func nonces(list []*Entry) []uint64 {
+	ret := make([]uint64, len(list))
+	for i, e := range list {
+		ret[i] = e.Nonce
+	}
+	return ret
+}
+
+func TestPool_Add(t *testing.T) {
+	p := New(Config{})
+	s, _ := signer.New()
+
+	e := testTx(t, s, 1, "1")
+	entry, err := p.Add(e)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if entry.From != s.PublicKey() || entry.Nonce != 1 || p.Len() != 1 || p.Size() != int64(len(e.Data)) {
+		t.Fatalf("Add() = %+v", entry)
+	}
+	if _, err := p.Add(e); !errors.Is(err, ErrDuplicate) {
+		t.Fatalf("Add() error = %v", err)
+	}
+
+	// bad signature
+	bad := &transaction.Envelope{Code: e.Code, Data: append([]byte(nil), e.Data...)}
+	bad.Data[len(bad.Data)-1] ^= 1
+	if _, err := p.Add(bad); !errors.Is(err, ErrSignature) {
+		t.Fatalf("Add() error = %v", err)
+	}
+
+	// unsigned
+	u, err := transaction.Unsigned(&transaction.UserData{Data: []byte{1}}, s.PublicKey(), 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := p.Add(&transaction.Envelope{Code: transaction.UserDataTx, Data: u.Data}); !errors.Is(err, ErrUnsigned) {
+		t.Fatalf("Add() error = %v", err)
+	}
+
+	// replacement of the same nonce
+	re, err := p.Add(testTx(t, s, 1, "2"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := p.Get(entry.ID); ok || p.Len() != 1 {
+		t.Fatal("transaction is not replaced")
+	}
+	if got, _ := p.Get(re.ID); got != re {
+		t.Fatal("replacement is not found")
+	}
+
+	// stale
+	p.Sync(s.PublicKey(), 1)
+	if p.Len() != 0 {
+		t.Fatalf("Len() = %v after sync", p.Len())
+	}
+	if _, err := p.Add(testTx(t, s, 1, "3")); !errors.Is(err, ErrStale) {
+		t.Fatalf("Add() error = %v", err)
+	}
+}
+
+func TestPool_ReadyAndGaps(t *testing.T) {
+	p := New(Config{})
+	a, _ := signer.New()
+	b, _ := signer.New()
+
+	for _, n := range []uint64{5, 3, 4, 7} {
+		if _, err := p.Add(testTx(t, a, n, "1")); err != nil {
+			t.Fatal(err)
+		}
+	}
+	p.Add(testTx(t, b, 2, "1"))
+
+	// unknown chain state: runs start from the lowest nonces
+	ready := p.Ready()
+	if got := nonces(ready); len(got) != 4 || got[0] != 3 || got[1] != 4 || got[2] != 5 || got[3] != 2 {
+		t.Fatalf("Ready() = %v", got)
+	}
+	if gaps := p.Gaps(); len(gaps) != 1 || gaps[0] != (nonce.Gap{Address: a.PublicKey(), Nonce: 6, Stalled: 1}) {
+		t.Fatalf("Gaps() = %v", gaps)
+	}
+
+	// the chain is behind: everything of "a" is stalled
+	p.Sync(a.PublicKey(), 1)
+	if got := nonces(p.Ready()); len(got) != 1 || got[0] != 2 {
+		t.Fatalf("Ready() = %v", got)
+	}
+	if gaps := p.Gaps(); len(gaps) != 2 || gaps[0].Nonce != 2 || gaps[0].Stalled != 4 || gaps[1].Nonce != 6 {
+		t.Fatalf("Gaps() = %v", gaps)
+	}
+}
+
+func TestPool_Evict(t *testing.T) {
+	now := time.Unix(1000, 0)
+	p := New(Config{MaxCount: 3, MaxAge: time.Minute})
+	p.now = func() time.Time { return now }
+	a, _ := signer.New()
+	b, _ := signer.New()
+
+	p.Add(testTx(t, a, 1, "1"))
+	now = now.Add(time.Second)
+	p.Add(testTx(t, a, 2, "1"))
+	now = now.Add(time.Second)
+	p.Add(testTx(t, b, 1, "1"))
+	now = now.Add(time.Second)
+
+	// the newest tail is evicted, i.e. the added transaction itself
+	if _, err := p.Add(testTx(t, b, 2, "1")); !errors.Is(err, ErrFull) || p.Len() != 3 {
+		t.Fatalf("Add() error = %v, len %v", err, p.Len())
+	}
+
+	// by age
+	now = now.Add(time.Minute - 2*time.Second)
+	p.Evict()
+	if got := nonces(p.Ready()); p.Len() != 2 || len(got) != 2 {
+		t.Fatalf("Ready() = %v, len %v", got, p.Len())
+	}
+
+	// by size
+	p.cfg = Config{MaxBytes: p.Size() - 1}
+	p.Evict()
+	if p.Len() != 1 {
+		t.Fatalf("Len() = %v", p.Len())
+	}
+}
+
+func TestPool_ReplaceFull(t *testing.T) {
+	s, _ := signer.New()
+	seal := func(size int) *transaction.Envelope {
+		e, _, err := transaction.Seal(&transaction.UserData{Data: make([]byte, size)}, s, 1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return e
+	}
+	small := seal(10)
+	p := New(Config{MaxBytes: int64(len(small.Data))})
+	old, err := p.Add(small)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the larger replacement doesn't fit, the replaced transaction is kept
+	if _, err := p.Add(seal(20)); !errors.Is(err, ErrFull) {
+		t.Fatalf("Add() error = %v", err)
+	}
+	if got, ok := p.Get(old.ID); !ok || got != old || p.Len() != 1 || p.Size() != int64(len(small.Data)) {
+		t.Fatalf("Get() = %v, len %v, size %v", ok, p.Len(), p.Size())
+	}
+	if got := nonces(p.Ready()); len(got) != 1 || got[0] != 1 {
+		t.Fatalf("Ready() = %v", got)
+	}
+
+	// the smaller one replaces
+	if _, err := p.Add(seal(5)); err != nil || p.Len() != 1 {
+		t.Fatalf("Add() error = %v, len %v", err, p.Len())
+	}
+}
+
+func TestPool_Idle(t *testing.T) {
+	p := New(Config{MaxIdle: 2})
+	a, _ := signer.New()
+	b, _ := signer.New()
+	c, _ := signer.New()
+
+	// the queue is dropped once empty, the last nonce is kept
+	if _, err := p.Add(testTx(t, a, 1, "1")); err != nil {
+		t.Fatal(err)
+	}
+	p.Sync(a.PublicKey(), 1)
+	if len(p.queues) != 0 || p.idle.Len() != 1 {
+		t.Fatalf("queues %v, idle %v", len(p.queues), p.idle.Len())
+	}
+	if _, err := p.Add(testTx(t, a, 1, "2")); !errors.Is(err, ErrStale) {
+		t.Fatalf("Add() error = %v", err)
+	}
+
+	// the least recently synced sender is forgotten
+	p.Sync(b.PublicKey(), 5)
+	p.Sync(c.PublicKey(), 5)
+	if len(p.queues) != 0 || p.idle.Len() != 2 {
+		t.Fatalf("queues %v, idle %v", len(p.queues), p.idle.Len())
+	}
+	if _, err := p.Add(testTx(t, a, 1, "3")); err != nil {
+		t.Fatalf("Add() error = %v", err)
+	}
+	if _, err := p.Add(testTx(t, b, 5, "1")); !errors.Is(err, ErrStale) {
+		t.Fatalf("Add() error = %v", err)
+	}
+
+	// a new transaction of an idle sender waits for the next nonce
+	if _, err := p.Add(testTx(t, c, 7, "1")); err != nil {
+		t.Fatal(err)
+	}
+	if gaps := p.Gaps(); len(gaps) != 1 || gaps[0] != (nonce.Gap{Address: c.PublicKey(), Nonce: 6, Stalled: 1}) {
+		t.Fatalf("Gaps() = %v", gaps)
+	}
+	if p.idle.Len() != 1 {
+		t.Fatalf("idle %v", p.idle.Len())
+	}
+}
+
+func TestPool_Concurrent(t *testing.T) {
+	p := New(Config{MaxCount: 50})
+	wg := sync.WaitGroup{}
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			s, _ := signer.New()
+			for n := uint64(1); n <= 20; n++ {
+				if _, err := p.Add(testTx(t, s, n, "1")); err != nil && !errors.Is(err, ErrFull) {
+					t.Error(err)
+				}
+				p.Ready()
+				p.Gaps()
+			}
+		}()
+	}
+	wg.Wait()
+	if p.Len() != 50 {
+		t.Fatalf("Len() = %v", p.Len())
+	}
+}
@@ -0,0 +1,279 @@
+package nonce
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+
+	mint "github.com/void616/gm.mint"
+	"github.com/void616/gm.mint/signer"
+	"github.com/void616/gm.mint/transaction"
+)
+
+// Manager allocates per-address nonces atomically.
+// Nonces of an address are sequential: a transaction is included into the chain only after all the transactions with lower nonces,
+// so a dropped nonce must be reused or a gap stalls later transactions (see Gaps)
+type Manager struct {
+	mu    sync.Mutex
+	store Store
+	addrs map[mint.PublicKey]*state
+}
+
+// state of an address
+type state struct {
+	// last nonce confirmed in the chain
+	confirmed uint64
+	// next nonce to allocate
+	next uint64
+	// allocated nonces, not confirmed or released yet
+	pending map[uint64]struct{}
+	// released nonces to reuse, sorted
+	released []uint64
+}
+
+// Gap is a nonce of an address that is not allocated, but some higher nonce is
+type Gap struct {
+	Address mint.PublicKey
+	Nonce   uint64
+	// Stalled is a count of allocated transactions with higher nonces
+	Stalled int
+}
+
+// New manager persisting the next nonces to the store
+func New(store Store) *Manager {
+	return &Manager{
+		store: store,
+		addrs: make(map[mint.PublicKey]*state),
+	}
+}
+
+// Next allocates a nonce for the address: the lowest released one, otherwise a new one
+func (m *Manager) Next(addr mint.PublicKey) (uint64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	s, err := m.state(addr)
+	if err != nil {
+		return 0, err
+	}
+	if len(s.released) > 0 {
+		n := s.released[0]
+		s.released = s.released[1:]
+		s.pending[n] = struct{}{}
+		return n, nil
+	}
+	n := s.next
+	if err := m.store.Save(addr, n+1); err != nil {
+		return 0, err
+	}
+	s.next++
+	s.pending[n] = struct{}{}
+	return n, nil
+}
+
+// Sign allocates a nonce and signs the transaction, the nonce is released on failure
+func (m *Manager) Sign(tx transaction.Transactioner, signer *signer.Signer) (*transaction.SignedTransaction, uint64, error) {
+	addr := signer.PublicKey()
+	n, err := m.Next(addr)
+	if err != nil {
+		return nil, 0, err
+	}
+	signed, err := tx.Sign(signer, n)
+	if err != nil {
+		if rerr := m.Release(addr, n); rerr != nil {
+			return nil, 0, fmt.Errorf("%v; release nonce: %v", err, rerr)
+		}
+		return nil, 0, err
+	}
+	return signed, n, nil
+}
+
+// Confirm marks the allocated nonce as included into the chain
+func (m *Manager) Confirm(addr mint.PublicKey, nonce uint64) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	s, err := m.state(addr)
+	if err != nil {
+		return err
+	}
+	delete(s.pending, nonce)
+	s.released = remove(s.released, nonce)
+	if nonce > s.confirmed {
+		s.confirmed = nonce
+	}
+	if nonce >= s.next {
+		if err := m.store.Save(addr, nonce+1); err != nil {
+			return err
+		}
+		s.next = nonce + 1
+	}
+	return nil
+}
+
+// Release returns the allocated nonce of a dropped transaction for reuse.
+// The highest allocated nonce is given back to the counter, others are reused by Next
+func (m *Manager) Release(addr mint.PublicKey, nonce uint64) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	s, err := m.state(addr)
+	if err != nil {
+		return err
+	}
+	if _, ok := s.pending[nonce]; !ok {
+		return fmt.Errorf("nonce %v of %v is not allocated", nonce, addr.StringMask())
+	}
+	delete(s.pending, nonce)
+	s.released = insert(s.released, nonce)
+
+	// shrink the counter while the tail is released
+	next := s.next
+	for len(s.released) > 0 && s.released[len(s.released)-1] == next-1 {
+		s.released = s.released[:len(s.released)-1]
+		next--
+	}
+	if next != s.next {
+		if err := m.store.Save(addr, next); err != nil {
+			return err
+		}
+		s.next = next
+	}
+	return nil
+}
+
+// Sync resets the address to the chain state: `last` is the last nonce of the address included into the chain.
+// Allocated and released nonces up to `last` are forgotten. The counter is moved forward if it's behind the chain,
+// and it's moved back to `last`+1 if no nonces remain allocated (e.g. the transactions were dropped by the node)
+func (m *Manager) Sync(addr mint.PublicKey, last uint64) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	s, err := m.state(addr)
+	if err != nil {
+		return err
+	}
+	pending := false
+	for n := range s.pending {
+		if n > last {
+			pending = true
+			break
+		}
+	}
+	next := s.next
+	if next <= last || !pending {
+		next = last + 1
+	}
+	if err := m.store.Save(addr, next); err != nil {
+		return err
+	}
+	s.confirmed, s.next = last, next
+	for n := range s.pending {
+		if n <= last {
+			delete(s.pending, n)
+		}
+	}
+	if pending {
+		i := sort.Search(len(s.released), func(i int) bool { return s.released[i] > last })
+		s.released = s.released[i:]
+	} else {
+		s.released = nil
+	}
+	return nil
+}
+
+// Pending returns allocated nonces of the address (not confirmed or released), sorted
+func (m *Manager) Pending(addr mint.PublicKey) []uint64 {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	s, ok := m.addrs[addr]
+	if !ok {
+		return nil
+	}
+	ret := make([]uint64, 0, len(s.pending))
+	for n := range s.pending {
+		ret = append(ret, n)
+	}
+	sort.Slice(ret, func(i, j int) bool { return ret[i] < ret[j] })
+	return ret
+}
+
+// Gaps returns the nonces (of all the known addresses) that would stall later allocated transactions:
+// nonces after the last confirmed one which are not allocated, while a higher nonce is
+func (m *Manager) Gaps() []Gap {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var ret []Gap
+	for addr, s := range m.addrs {
+		ret = append(ret, s.gaps(addr)...)
+	}
+	sort.Slice(ret, func(i, j int) bool {
+		if ret[i].Address != ret[j].Address {
+			return ret[i].Address.String() < ret[j].Address.String()
+		}
+		return ret[i].Nonce < ret[j].Nonce
+	})
+	return ret
+}
+
+// ---
+
+// state of the address, loaded from the store on the first access
+func (m *Manager) state(addr mint.PublicKey) (*state, error) {
+	if s, ok := m.addrs[addr]; ok {
+		return s, nil
+	}
+	next, ok, err := m.store.Load(addr)
+	if err != nil {
+		return nil, err
+	}
+	if !ok || next == 0 {
+		next = 1
+	}
+	s := &state{
+		confirmed: next - 1,
+		next:      next,
+		pending:   make(map[uint64]struct{}),
+	}
+	m.addrs[addr] = s
+	return s, nil
+}
+
+func (s *state) gaps(addr mint.PublicKey) []Gap {
+	pending := make([]uint64, 0, len(s.pending))
+	for n := range s.pending {
+		pending = append(pending, n)
+	}
+	sort.Slice(pending, func(i, j int) bool { return pending[i] < pending[j] })
+
+	var ret []Gap
+	expect := s.confirmed + 1
+	for i, n := range pending {
+		for ; expect < n; expect++ {
+			ret = append(ret, Gap{Address: addr, Nonce: expect, Stalled: len(pending) - i})
+		}
+		expect = n + 1
+	}
+	return ret
+}
+
+func insert(list []uint64, n uint64) []uint64 {
+	i := sort.Search(len(list), func(i int) bool { return list[i] >= n })
+	if i < len(list) && list[i] == n {
+		return list
+	}
+	list = append(list, 0)
+	copy(list[i+1:], list[i:])
+	list[i] = n
+	return list
+}
+
+func remove(list []uint64, n uint64) []uint64 {
+	i := sort.Search(len(list), func(i int) bool { return list[i] >= n })
+	if i < len(list) && list[i] == n {
+		return append(list[:i], list[i+1:]...)
+	}
+	return list
+}
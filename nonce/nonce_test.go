@@ -0,0 +1,202 @@
+package nonce
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sync"
+	"testing"
+
+	mint "github.com/void616/gm.mint"
+	"github.com/void616/gm.mint/amount"
+	"github.com/void616/gm.mint/signer"
+	"github.com/void616/gm.mint/transaction"
+)
+
+func TestManager_Concurrent(t *testing.T) {
+	m := New(NewMemoryStore())
+	addr := mint.PublicKey{1}
+
+	const workers, each = 8, 100
+	got := make(chan uint64, workers*each)
+	wg := sync.WaitGroup{}
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < each; j++ {
+				n, err := m.Next(addr)
+				if err != nil {
+					t.Error(err)
+					return
+				}
+				got <- n
+			}
+		}()
+	}
+	wg.Wait()
+	close(got)
+
+	seen := make(map[uint64]bool)
+	for n := range got {
+		if seen[n] || n < 1 || n > workers*each {
+			t.Fatalf("nonce %v is duplicated or out of range", n)
+		}
+		seen[n] = true
+	}
+	if len(m.Gaps()) != 0 {
+		t.Fatalf("Gaps() = %v", m.Gaps())
+	}
+}
+
+func TestManager_ReleaseAndGaps(t *testing.T) {
+	m := New(NewMemoryStore())
+	addr := mint.PublicKey{1}
+
+	for i := 0; i < 5; i++ {
+		m.Next(addr) // 1..5
+	}
+	if err := m.Confirm(addr, 1); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Release(addr, 3); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Release(addr, 3); err == nil {
+		t.Fatal("double release is not failed")
+	}
+	if want := []Gap{{Address: addr, Nonce: 3, Stalled: 2}}; !reflect.DeepEqual(m.Gaps(), want) {
+		t.Fatalf("Gaps() = %v, want %v", m.Gaps(), want)
+	}
+
+	// the gap is reused first
+	if n, _ := m.Next(addr); n != 3 {
+		t.Fatalf("Next() = %v, want 3", n)
+	}
+	if len(m.Gaps()) != 0 {
+		t.Fatalf("Gaps() = %v", m.Gaps())
+	}
+
+	// the tail is given back to the counter
+	m.Release(addr, 5)
+	m.Release(addr, 4)
+	if n, _ := m.Next(addr); n != 4 {
+		t.Fatalf("Next() = %v, want 4", n)
+	}
+	if want := []uint64{2, 3, 4}; !reflect.DeepEqual(m.Pending(addr), want) {
+		t.Fatalf("Pending() = %v, want %v", m.Pending(addr), want)
+	}
+}
+
+func TestManager_Sync(t *testing.T) {
+	m := New(NewMemoryStore())
+	addr := mint.PublicKey{1}
+
+	for i := 0; i < 3; i++ {
+		m.Next(addr) // 1..3
+	}
+	m.Release(addr, 2)
+
+	// the chain is ahead
+	if err := m.Sync(addr, 10); err != nil {
+		t.Fatal(err)
+	}
+	if n, _ := m.Next(addr); n != 11 {
+		t.Fatalf("Next() = %v, want 11", n)
+	}
+	if want := []uint64{11}; !reflect.DeepEqual(m.Pending(addr), want) {
+		t.Fatalf("Pending() = %v, want %v", m.Pending(addr), want)
+	}
+
+	// the chain is behind: nonces 11.. are allocated but not included yet
+	m.Next(addr) // 12
+	if err := m.Sync(addr, 9); err != nil {
+		t.Fatal(err)
+	}
+	if want := []Gap{{Address: addr, Nonce: 10, Stalled: 2}}; !reflect.DeepEqual(m.Gaps(), want) {
+		t.Fatalf("Gaps() = %v, want %v", m.Gaps(), want)
+	}
+}
+
+func TestManager_SyncReset(t *testing.T) {
+	store := NewMemoryStore()
+	m := New(store)
+	addr := mint.PublicKey{1}
+	for i := 0; i < 5; i++ {
+		m.Next(addr) // 1..5
+	}
+
+	// restart: transactions 3..5 are lost, nothing is pending after the chain
+	m = New(store)
+	if err := m.Sync(addr, 2); err != nil {
+		t.Fatal(err)
+	}
+	if n, _ := m.Next(addr); n != 3 {
+		t.Fatalf("Next() = %v, want 3", n)
+	}
+	if next, _, _ := store.Load(addr); next != 4 {
+		t.Fatalf("stored next = %v, want 4", next)
+	}
+
+	// nonce 3 is pending, the counter is kept
+	m.Next(addr) // 4
+	m.Release(addr, 3)
+	if err := m.Sync(addr, 2); err != nil {
+		t.Fatal(err)
+	}
+	if n, _ := m.Next(addr); n != 3 {
+		t.Fatalf("Next() = %v, want 3", n)
+	}
+	if want := []uint64{3, 4}; !reflect.DeepEqual(m.Pending(addr), want) {
+		t.Fatalf("Pending() = %v, want %v", m.Pending(addr), want)
+	}
+}
+
+func TestManager_Sign(t *testing.T) {
+	m := New(NewMemoryStore())
+	s, _ := signer.New()
+
+	_, n, err := m.Sign(&transaction.TransferAsset{Address: mint.PublicKey{1}, Amount: amount.MustFromString("1")}, s)
+	if err != nil || n != 1 {
+		t.Fatalf("Sign() = %v, %v", n, err)
+	}
+	// invalid transaction, the nonce is released
+	if _, _, err := m.Sign(&transaction.UserData{}, s); err == nil {
+		t.Fatal("Sign() is not failed")
+	}
+	if n, _ := m.Next(s.PublicKey()); n != 2 {
+		t.Fatalf("Next() = %v, want 2", n)
+	}
+}
+
+func TestFileStore(t *testing.T) {
+	dir, err := ioutil.TempDir("", "nonce")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "nonce.json")
+	a, b := mint.PublicKey{1}, mint.PublicKey{2}
+
+	fs, err := NewFileStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := New(fs)
+	m.Next(a)
+	m.Next(a)
+	m.Sync(b, 1<<60)
+
+	fs, err = NewFileStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m = New(fs)
+	if n, _ := m.Next(a); n != 3 {
+		t.Fatalf("Next() = %v, want 3", n)
+	}
+	if n, _ := m.Next(b); n != 1<<60+1 {
+		t.Fatalf("Next() = %v, want %v", n, uint64(1<<60+1))
+	}
+}
@@ -0,0 +1,150 @@
+package nonce
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+
+	mint "github.com/void616/gm.mint"
+)
+
+// Store persists the next nonce of the addresses
+type Store interface {
+	// Load returns the next nonce of the address, `ok` is false if the address is unknown
+	Load(addr mint.PublicKey) (next uint64, ok bool, err error)
+	// Save the next nonce of the address
+	Save(addr mint.PublicKey, next uint64) error
+}
+
+// MemoryStore is an in-memory store
+type MemoryStore struct {
+	mu   sync.Mutex
+	next map[mint.PublicKey]uint64
+}
+
+// NewMemoryStore instance
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		next: make(map[mint.PublicKey]uint64),
+	}
+}
+
+// Load impl
+func (s *MemoryStore) Load(addr mint.PublicKey) (uint64, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	n, ok := s.next[addr]
+	return n, ok, nil
+}
+
+// Save impl
+func (s *MemoryStore) Save(addr mint.PublicKey, next uint64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.next[addr] = next
+	return nil
+}
+
+// FileStore is a JSON file store: an object of the next nonces (as strings) by address.
+// The file is rewritten on every save via a temporary file
+type FileStore struct {
+	mu   sync.Mutex
+	path string
+	next map[mint.PublicKey]uint64
+}
+
+// NewFileStore loads the file, the file is created on the first save if it doesn't exist
+func NewFileStore(path string) (*FileStore, error) {
+	s := &FileStore{
+		path: path,
+		next: make(map[mint.PublicKey]uint64),
+	}
+	b, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	m := make(map[string]string)
+	if err := json.Unmarshal(b, &m); err != nil {
+		return nil, fmt.Errorf("nonce file %v: %v", path, err)
+	}
+	for k, v := range m {
+		addr, err := mint.ParsePublicKey(k)
+		if err != nil {
+			return nil, fmt.Errorf("nonce file %v: address %v: %v", path, k, err)
+		}
+		n, err := strconv.ParseUint(v, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("nonce file %v: nonce of %v: %v", path, k, err)
+		}
+		s.next[addr] = n
+	}
+	return s, nil
+}
+
+// Load impl
+func (s *FileStore) Load(addr mint.PublicKey) (uint64, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	n, ok := s.next[addr]
+	return n, ok, nil
+}
+
+// Save impl
+func (s *FileStore) Save(addr mint.PublicKey, next uint64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	prev, had := s.next[addr]
+	s.next[addr] = next
+	if err := s.write(); err != nil {
+		if had {
+			s.next[addr] = prev
+		} else {
+			delete(s.next, addr)
+		}
+		return err
+	}
+	return nil
+}
+
+func (s *FileStore) write() error {
+	m := make(map[string]string, len(s.next))
+	for k, v := range s.next {
+		m[k.String()] = strconv.FormatUint(v, 10)
+	}
+	b, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	f, err := ioutil.TempFile(filepath.Dir(s.path), filepath.Base(s.path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(b); err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return err
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(f.Name())
+		return err
+	}
+	if err := os.Rename(f.Name(), s.path); err != nil {
+		os.Remove(f.Name())
+		return err
+	}
+	return nil
+}
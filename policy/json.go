@@ -0,0 +1,135 @@
+package policy
+
+import (
+	"encoding/json"
+	"fmt"
+
+	mint "github.com/void616/gm.mint"
+	"github.com/void616/gm.mint/transaction"
+)
+
+// policyJSON is JSON layout of Policy
+type policyJSON struct {
+	Rules   []ruleJSON `json:"rules"`
+	FeeFree []tagJSON  `json:"fee_free"`
+}
+
+// ruleJSON is JSON layout of Rule
+type ruleJSON struct {
+	Code  transaction.Code `json:"code"`
+	Token *tokenJSON       `json:"token,omitempty"`
+	Tag   *tagJSON         `json:"tag,omitempty"`
+	AnyOf []tagJSON        `json:"any_of"`
+}
+
+// tokenJSON is a token name
+type tokenJSON mint.Token
+
+// tagJSON is a wallet tag name
+type tagJSON mint.WalletTag
+
+// MarshalJSON impl
+func (p *Policy) MarshalJSON() ([]byte, error) {
+	form := policyJSON{
+		Rules:   make([]ruleJSON, len(p.Rules)),
+		FeeFree: toTagsJSON(p.FeeFree),
+	}
+	for i, r := range p.Rules {
+		form.Rules[i] = ruleJSON{Code: r.Code, Token: (*tokenJSON)(r.Token), Tag: (*tagJSON)(r.Tag), AnyOf: toTagsJSON(r.AnyOf)}
+	}
+	return json.Marshal(&form)
+}
+
+// UnmarshalJSON impl
+func (p *Policy) UnmarshalJSON(b []byte) error {
+	var form policyJSON
+	if err := json.Unmarshal(b, &form); err != nil {
+		return err
+	}
+	*p = Policy{
+		Rules:   make([]Rule, len(form.Rules)),
+		FeeFree: fromTagsJSON(form.FeeFree),
+	}
+	for i, r := range form.Rules {
+		p.Rules[i] = Rule{Code: r.Code, Token: (*mint.Token)(r.Token), Tag: (*mint.WalletTag)(r.Tag), AnyOf: fromTagsJSON(r.AnyOf)}
+	}
+	return nil
+}
+
+// MarshalJSON impl
+func (t tokenJSON) MarshalJSON() ([]byte, error) {
+	s := mint.Token(t).String()
+	if s == "" {
+		return nil, fmt.Errorf("unknown token with code `%v`", uint16(t))
+	}
+	return json.Marshal(s)
+}
+
+// UnmarshalJSON impl, the name or the code of the token
+func (t *tokenJSON) UnmarshalJSON(b []byte) error {
+	var code uint16
+	if err := json.Unmarshal(b, &code); err == nil {
+		*t = tokenJSON(code)
+		return nil
+	}
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		return err
+	}
+	x, err := mint.ParseToken(s)
+	if err != nil {
+		return err
+	}
+	*t = tokenJSON(x)
+	return nil
+}
+
+// MarshalJSON impl
+func (t tagJSON) MarshalJSON() ([]byte, error) {
+	s := mint.WalletTag(t).String()
+	if s == "" {
+		return nil, fmt.Errorf("unknown wallet tag with code `%v`", uint8(t))
+	}
+	return json.Marshal(s)
+}
+
+// UnmarshalJSON impl, the name or the code of the wallet tag
+func (t *tagJSON) UnmarshalJSON(b []byte) error {
+	var code uint8
+	if err := json.Unmarshal(b, &code); err == nil {
+		*t = tagJSON(code)
+		return nil
+	}
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		return err
+	}
+	x, err := mint.ParseWalletTag(s)
+	if err != nil {
+		return err
+	}
+	*t = tagJSON(x)
+	return nil
+}
+
+func toTagsJSON(tags []mint.WalletTag) []tagJSON {
+	if tags == nil {
+		return nil
+	}
+	ret := make([]tagJSON, len(tags))
+	for i, t := range tags {
+		ret[i] = tagJSON(t)
+	}
+	return ret
+}
+
+func fromTagsJSON(tags []tagJSON) []mint.WalletTag {
+	if tags == nil {
+		return nil
+	}
+	ret := make([]mint.WalletTag, len(tags))
+	for i, t := range tags {
+		ret[i] = mint.WalletTag(t)
+	}
+	return ret
+}
@@ -0,0 +1,252 @@
+package policy
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	mint "github.com/void616/gm.mint"
+	"github.com/void616/gm.mint/transaction"
+)
+
+// Policy is a set of rules stating which wallet tags may sign which transactions and which tags are fee-free.
+// In JSON tokens and wallet tags are names, like {"rules":[{"code":"transfer_asset","token":"GOLD","any_of":["approved"]}],"fee_free":["nofee"]},
+// codes are accepted too
+type Policy struct {
+	// Rules of the transactions. A transaction without matching rules is allowed to anyone,
+	// otherwise the signer must satisfy at least one of the matching rules
+	Rules []Rule
+	// FeeFree are the tags exempting the signer from the fee
+	FeeFree []mint.WalletTag
+}
+
+// Rule requires the signer to have any of the tags to sign a transaction
+type Rule struct {
+	// Code of the transaction
+	Code transaction.Code
+	// Token of TransferAsset, optional
+	Token *mint.Token
+	// Tag being set or unset by SetWalletTag and UnsetWalletTag, optional
+	Tag *mint.WalletTag
+	// AnyOf are the tags allowed to sign, empty means anyone
+	AnyOf []mint.WalletTag
+}
+
+// DeniedError explains why the transaction is denied
+type DeniedError struct {
+	// Code of the transaction
+	Code transaction.Code
+	// Condition of the matched rules, like "tag owner" (optional)
+	Condition string
+	// Required are the tags allowed to sign the transaction
+	Required []mint.WalletTag
+	// Tags of the signer
+	Tags []mint.WalletTag
+}
+
+// Error impl
+func (e *DeniedError) Error() string {
+	what := e.Code.String()
+	if e.Condition != "" {
+		what += " (" + e.Condition + ")"
+	}
+	return fmt.Sprintf("%v is denied: requires any of tags [%v], signer has [%v]", what, tagList(e.Required), tagList(e.Tags))
+}
+
+var (
+	gold       = mint.TokenGOLD
+	approved   = mint.WalletTagApproved
+	deposital  = mint.WalletTagDeposital
+	supervisor = []mint.WalletTag{mint.WalletTagSupervisor}
+)
+
+var defaultPolicy = &Policy{
+	Rules: []Rule{
+		{Code: transaction.RegisterNodeTx, AnyOf: supervisor},
+		{Code: transaction.UnregisterNodeTx, AnyOf: supervisor},
+		// supervisor sets any tag, authority sets "approved", exchange sets "deposital"
+		{Code: transaction.SetWalletTagTx, AnyOf: supervisor},
+		{Code: transaction.SetWalletTagTx, Tag: &approved, AnyOf: []mint.WalletTag{mint.WalletTagAuthority}},
+		{Code: transaction.SetWalletTagTx, Tag: &deposital, AnyOf: []mint.WalletTag{mint.WalletTagExchange}},
+		{Code: transaction.UnsetWalletTagTx, AnyOf: supervisor},
+		{Code: transaction.UnsetWalletTagTx, Tag: &approved, AnyOf: []mint.WalletTag{mint.WalletTagAuthority}},
+		{Code: transaction.UnsetWalletTagTx, Tag: &deposital, AnyOf: []mint.WalletTag{mint.WalletTagExchange}},
+		// GOLD is sent by KYC-proved users, exchanges and system wallets
+		{Code: transaction.TransferAssetTx, Token: &gold, AnyOf: []mint.WalletTag{
+			mint.WalletTagApproved, mint.WalletTagDeposital, mint.WalletTagEmission, mint.WalletTagOwner,
+		}},
+		{Code: transaction.DistributionFeeTx, AnyOf: []mint.WalletTag{mint.WalletTagOwner}},
+	},
+	FeeFree: []mint.WalletTag{
+		mint.WalletTagSupervisor,
+		mint.WalletTagOwner,
+		mint.WalletTagEmission,
+		mint.WalletTagNoFee,
+	},
+}
+
+// Default policy (a copy) following the wallet tags description (see mint.WalletTag)
+func Default() *Policy {
+	return defaultPolicy.Copy()
+}
+
+// ParsePolicy parses a policy from JSON and validates it
+func ParsePolicy(b []byte) (*Policy, error) {
+	p := &Policy{}
+	if err := json.Unmarshal(b, p); err != nil {
+		return nil, err
+	}
+	if err := p.Validate(); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// Validate the policy
+func (p *Policy) Validate() error {
+	for i, r := range p.Rules {
+		switch {
+		case !transaction.ValidCode(uint16(r.Code)):
+			return fmt.Errorf("rule %v: unknown transaction code %v", i, uint16(r.Code))
+		case r.Token != nil && r.Code != transaction.TransferAssetTx:
+			return fmt.Errorf("rule %v: token is applicable to %v only", i, transaction.TransferAssetTx)
+		case r.Token != nil && !mint.ValidToken(uint16(*r.Token)):
+			return fmt.Errorf("rule %v: unknown token %v", i, uint16(*r.Token))
+		case r.Tag != nil && r.Code != transaction.SetWalletTagTx && r.Code != transaction.UnsetWalletTagTx:
+			return fmt.Errorf("rule %v: tag is applicable to %v and %v only", i, transaction.SetWalletTagTx, transaction.UnsetWalletTagTx)
+		case r.Tag != nil && !mint.ValidWalletTag(uint8(*r.Tag)):
+			return fmt.Errorf("rule %v: unknown wallet tag %v", i, uint8(*r.Tag))
+		}
+		for _, t := range r.AnyOf {
+			if !mint.ValidWalletTag(uint8(t)) {
+				return fmt.Errorf("rule %v: unknown wallet tag %v", i, uint8(t))
+			}
+		}
+	}
+	for _, t := range p.FeeFree {
+		if !mint.ValidWalletTag(uint8(t)) {
+			return fmt.Errorf("fee-free: unknown wallet tag %v", uint8(t))
+		}
+	}
+	return nil
+}
+
+// Copy of the policy
+func (p *Policy) Copy() *Policy {
+	ret := &Policy{
+		Rules:   make([]Rule, len(p.Rules)),
+		FeeFree: append([]mint.WalletTag(nil), p.FeeFree...),
+	}
+	for i, r := range p.Rules {
+		ret.Rules[i] = Rule{Code: r.Code, AnyOf: append([]mint.WalletTag(nil), r.AnyOf...)}
+		if r.Token != nil {
+			v := *r.Token
+			ret.Rules[i].Token = &v
+		}
+		if r.Tag != nil {
+			v := *r.Tag
+			ret.Rules[i].Tag = &v
+		}
+	}
+	return ret
+}
+
+// ---
+
+// Authorize evaluates the transaction against the signer's tags, returns DeniedError if the signer is not allowed to sign it
+func (p *Policy) Authorize(tx transaction.Transactioner, tags []mint.WalletTag) error {
+	var (
+		matched   bool
+		required  []mint.WalletTag
+		condition string
+	)
+	for _, r := range p.Rules {
+		if !r.matches(tx) {
+			continue
+		}
+		if len(r.AnyOf) == 0 || hasAny(tags, r.AnyOf) {
+			return nil
+		}
+		matched = true
+		required = appendUnique(required, r.AnyOf...)
+		if c := r.condition(); c != "" {
+			condition = c
+		}
+	}
+	if !matched {
+		return nil
+	}
+	return &DeniedError{
+		Code:      tx.Code(),
+		Condition: condition,
+		Required:  required,
+		Tags:      append([]mint.WalletTag(nil), tags...),
+	}
+}
+
+// IsFeeFree returns true if any of the signer's tags exempts it from the fee
+func (p *Policy) IsFeeFree(tags []mint.WalletTag) bool {
+	return hasAny(tags, p.FeeFree)
+}
+
+// matches checks the rule is applicable to the transaction
+func (r *Rule) matches(tx transaction.Transactioner) bool {
+	if tx.Code() != r.Code {
+		return false
+	}
+	if r.Token != nil {
+		if t, ok := tx.(*transaction.TransferAsset); !ok || t.Token != *r.Token {
+			return false
+		}
+	}
+	if r.Tag != nil {
+		switch t := tx.(type) {
+		case *transaction.SetWalletTag:
+			return t.Tag == *r.Tag
+		case *transaction.UnsetWalletTag:
+			return t.Tag == *r.Tag
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// condition of the rule as a string
+func (r *Rule) condition() string {
+	switch {
+	case r.Token != nil:
+		return "token " + r.Token.String()
+	case r.Tag != nil:
+		return "tag " + r.Tag.String()
+	}
+	return ""
+}
+
+func hasAny(tags, any []mint.WalletTag) bool {
+	for _, t := range tags {
+		for _, a := range any {
+			if t == a {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func appendUnique(list []mint.WalletTag, tags ...mint.WalletTag) []mint.WalletTag {
+	for _, t := range tags {
+		if !hasAny(list, []mint.WalletTag{t}) {
+			list = append(list, t)
+		}
+	}
+	return list
+}
+
+func tagList(tags []mint.WalletTag) string {
+	s := make([]string, len(tags))
+	for i, t := range tags {
+		s[i] = t.String()
+	}
+	return strings.Join(s, " ")
+}
@@ -0,0 +1,115 @@
+package policy
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	mint "github.com/void616/gm.mint"
+	"github.com/void616/gm.mint/amount"
+	"github.com/void616/gm.mint/transaction"
+)
+
+func TestPolicy_Authorize(t *testing.T) {
+	p := Default()
+	tags := func(t ...mint.WalletTag) []mint.WalletTag { return t }
+	one := amount.MustFromString("1")
+
+	tests := []struct {
+		name     string
+		tx       transaction.Transactioner
+		tags     []mint.WalletTag
+		required []mint.WalletTag
+	}{
+		{"user data", &transaction.UserData{Data: []byte{1}}, nil, nil},
+		{"mnt transfer", &transaction.TransferAsset{Token: mint.TokenMNT, Amount: one}, nil, nil},
+		{"gold transfer approved", &transaction.TransferAsset{Token: mint.TokenGOLD, Amount: one}, tags(mint.WalletTagApproved), nil},
+		{"gold transfer", &transaction.TransferAsset{Token: mint.TokenGOLD, Amount: one}, tags(mint.WalletTagNoFee),
+			tags(mint.WalletTagApproved, mint.WalletTagDeposital, mint.WalletTagEmission, mint.WalletTagOwner)},
+		{"register node", &transaction.RegisterNode{}, tags(mint.WalletTagSupervisor), nil},
+		{"register node denied", &transaction.RegisterNode{}, tags(mint.WalletTagOwner), tags(mint.WalletTagSupervisor)},
+		{"set owner by supervisor", &transaction.SetWalletTag{Tag: mint.WalletTagOwner}, tags(mint.WalletTagSupervisor), nil},
+		{"set owner by authority", &transaction.SetWalletTag{Tag: mint.WalletTagOwner}, tags(mint.WalletTagAuthority), tags(mint.WalletTagSupervisor)},
+		{"set approved by authority", &transaction.SetWalletTag{Tag: mint.WalletTagApproved}, tags(mint.WalletTagAuthority), nil},
+		{"unset deposital by exchange", &transaction.UnsetWalletTag{Tag: mint.WalletTagDeposital}, tags(mint.WalletTagExchange), nil},
+		{"unset deposital", &transaction.UnsetWalletTag{Tag: mint.WalletTagDeposital}, tags(mint.WalletTagAuthority),
+			tags(mint.WalletTagSupervisor, mint.WalletTagExchange)},
+		{"distribution fee denied", &transaction.DistributionFee{}, tags(mint.WalletTagSupervisor), tags(mint.WalletTagOwner)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := p.Authorize(tt.tx, tt.tags)
+			if tt.required == nil {
+				if err != nil {
+					t.Fatalf("Authorize() error = %v", err)
+				}
+				return
+			}
+			var derr *DeniedError
+			if !errors.As(err, &derr) || derr.Code != tt.tx.Code() || !reflect.DeepEqual(derr.Required, tt.required) {
+				t.Fatalf("Authorize() error = %v, want required %v", err, tagList(tt.required))
+			}
+		})
+	}
+
+	err := p.Authorize(&transaction.SetWalletTag{Tag: mint.WalletTagApproved}, nil)
+	if want := "set_wallet_tag (tag approved) is denied: requires any of tags [supervisor authority], signer has []"; err == nil || err.Error() != want {
+		t.Fatalf("Authorize() error = %v, want %v", err, want)
+	}
+}
+
+func TestPolicy_IsFeeFree(t *testing.T) {
+	p := Default()
+	if !p.IsFeeFree([]mint.WalletTag{mint.WalletTagApproved, mint.WalletTagNoFee}) {
+		t.Fatal("nofee is not fee-free")
+	}
+	if p.IsFeeFree([]mint.WalletTag{mint.WalletTagApproved}) || p.IsFeeFree(nil) {
+		t.Fatal("approved is fee-free")
+	}
+}
+
+func TestParsePolicy(t *testing.T) {
+	b, err := json.Marshal(Default())
+	if err != nil {
+		t.Fatal(err)
+	}
+	p, err := ParsePolicy(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(p, Default()) || !strings.Contains(string(b), `{"code":"transfer_asset","token":"GOLD","any_of":["approved",`) {
+		t.Fatalf("ParsePolicy() = %s", b)
+	}
+
+	// custom rules: anyone may register a node, user data requires "approved"
+	p, err = ParsePolicy([]byte(`{
+		"rules": [
+			{"code": "register_node", "any_of": []},
+			{"code": 7, "any_of": ["approved"]}
+		],
+		"fee_free": ["nofee"]
+	}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := p.Authorize(&transaction.RegisterNode{}, nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := p.Authorize(&transaction.UserData{}, []mint.WalletTag{mint.WalletTagNoFee}); err == nil {
+		t.Fatal("user data is allowed")
+	}
+
+	for _, doc := range []string{
+		`{"rules": [{"code": "nope"}]}`,
+		`{"rules": [{"code": "user_data", "token": "gold"}]}`,
+		`{"rules": [{"code": "transfer_asset", "tag": "owner"}]}`,
+		`{"rules": [{"code": "user_data", "any_of": [42]}]}`,
+		`{"fee_free": [0]}`,
+	} {
+		if _, err := ParsePolicy([]byte(doc)); err == nil {
+			t.Errorf("ParsePolicy(%v) is not failed", doc)
+		}
+	}
+}
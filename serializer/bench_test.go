@@ -0,0 +1,99 @@
+package serializer
+
+import (
+	"bytes"
+	"testing"
+
+	mint "github.com/void616/gm.mint"
+	"github.com/void616/gm.mint/amount"
+)
+
+// benchBlock makes a block-like data: a header, 16 signers and 1000 transfer transactions
+func benchBlock(tb testing.TB) []byte {
+	ser := NewSerializer()
+	ser.PutUint16(1)                            // version
+	ser.PutBytes(make([]byte, mint.DigestSize)) // previous block digest
+	ser.PutUint16(0)                            // consensus round
+	ser.PutBytes(make([]byte, mint.DigestSize)) // merkle root
+	ser.PutUint64(19527035308000000)            // time
+	ser.PutUint16(1000)                         // transactions
+	ser.PutBytes(make([]byte, 32))              // block
+	ser.PutUint16(16)                           // signers
+	for i := 0; i < 16; i++ {
+		ser.PutPublicKey(mint.PublicKey{byte(i)})
+		ser.PutBytes(make([]byte, mint.SignatureSize))
+	}
+	for i := 0; i < 1000; i++ {
+		ser.PutUint16(10)                              // code
+		ser.PutUint64(uint64(i))                       // nonce
+		ser.PutUint16(1)                               // token
+		ser.PutPublicKey(mint.PublicKey{byte(i)})      // from
+		ser.PutPublicKey(mint.PublicKey{byte(i + 1)})  // to
+		ser.PutAmount(amount.MustFromString("1.666"))  // amount
+		ser.PutByte(1)                                 // signed
+		ser.PutBytes(make([]byte, mint.SignatureSize)) // signature
+	}
+	b, err := ser.Data()
+	if err != nil {
+		tb.Fatal(err)
+	}
+	return b
+}
+
+func benchDecode(b *testing.B, d *Deserializer) {
+	d.GetUint16()
+	d.GetDigest()
+	d.GetUint16()
+	d.GetDigest()
+	d.GetUint64()
+	txs := d.GetUint16()
+	d.GetUint256()
+	signers := d.GetUint16()
+	for i := uint16(0); i < signers; i++ {
+		d.GetPublicKey()
+		d.GetSignature()
+	}
+	for i := uint16(0); i < txs; i++ {
+		d.GetUint16()
+		d.GetUint64()
+		d.GetUint16()
+		d.GetPublicKey()
+		d.GetPublicKey()
+		d.GetAmount()
+		d.GetByte()
+		d.GetSignature()
+	}
+	if err := d.Error(); err != nil {
+		b.Fatal(err)
+	}
+}
+
+func BenchmarkDeserializer_Stream(b *testing.B) {
+	dat := benchBlock(b)
+	b.SetBytes(int64(len(dat)))
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		benchDecode(b, NewStreamDeserializer(bytes.NewReader(dat)))
+	}
+}
+
+func BenchmarkDeserializer_Bytes(b *testing.B) {
+	dat := benchBlock(b)
+	b.SetBytes(int64(len(dat)))
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		benchDecode(b, NewDeserializer(dat))
+	}
+}
+
+func BenchmarkDeserializer_Slice(b *testing.B) {
+	dat := benchBlock(b)
+	b.SetBytes(int64(len(dat)))
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		benchDecode(b, NewSliceDeserializer(dat))
+	}
+}
@@ -2,7 +2,7 @@ package serializer
 
 import (
 	"bytes"
-	"encoding/hex"
+	"encoding/binary"
 	"fmt"
 	"io"
 	"math/big"
@@ -11,19 +11,33 @@ import (
 	"github.com/void616/gm.mint/amount"
 )
 
-// NewDeserializer instance
+// NewDeserializer instance, returned bytes are copies of the data
 func NewDeserializer(data []byte) *Deserializer {
 	return &Deserializer{
-		src: bytes.NewBuffer(data),
-		err: nil,
+		buf:      data,
+		slice:    true,
+		err:      nil,
+		maxField: DefaultLimits.MaxFieldBytes,
+	}
+}
+
+// NewSliceDeserializer instance, returned bytes are sub-slices of the data (zero-copy), so the data must not be modified
+func NewSliceDeserializer(data []byte) *Deserializer {
+	return &Deserializer{
+		buf:      data,
+		slice:    true,
+		zeroCopy: true,
+		err:      nil,
+		maxField: DefaultLimits.MaxFieldBytes,
 	}
 }
 
 // NewStreamDeserializer instance
 func NewStreamDeserializer(r io.Reader) *Deserializer {
 	return &Deserializer{
-		src: r,
-		err: nil,
+		src:      r,
+		err:      nil,
+		maxField: DefaultLimits.MaxFieldBytes,
 	}
 }
 
@@ -31,6 +45,68 @@ func NewStreamDeserializer(r io.Reader) *Deserializer {
 type Deserializer struct {
 	src io.Reader
 	err error
+	// data and flags of a slice-backed instance
+	buf      []byte
+	slice    bool
+	zeroCopy bool
+	// buffer for fixed size values of a stream-backed instance
+	scratch [64]byte
+	// offset of the next byte to read
+	off int64
+	// prefix of field labels
+	prefix string
+	// current field label and its offset
+	field    string
+	fieldOff int64
+	// the last read field label and its offset
+	last    string
+	lastOff int64
+	// limits: a single field size and the whole data size
+	maxField uint32
+	maxTotal int64
+	// trace of labeled fields
+	trace func(Traced)
+	// reject non-canonical encodings
+	strict bool
+}
+
+// Traced is a labeled field read by a deserializer
+type Traced struct {
+	// Field label (with the prefix)
+	Field string
+	// Offset of the field
+	Offset int64
+	// Length of the field in bytes
+	Length int64
+	// Value decoded: byte, []byte, uint16, uint32, uint64, *big.Int, string, mint.PublicKey, mint.Digest, mint.Signature or *amount.Amount
+	Value interface{}
+}
+
+// Error is a deserialization error
+type Error struct {
+	// Offset of the field (or the read) in the source
+	Offset int64
+	// Field label, optional
+	Field string
+	// Expected bytes count to read
+	Expected int
+	// Got bytes count actually read
+	Got int
+	// Err is an underlying error
+	Err error
+}
+
+// Error impl, like: TransferAsset.Amount at offset 74: unexpected EOF
+func (e *Error) Error() string {
+	if e.Field != "" {
+		return fmt.Sprintf("%v at offset %v: %v", e.Field, e.Offset, e.Err)
+	}
+	return fmt.Sprintf("offset %v: %v", e.Offset, e.Err)
+}
+
+// Unwrap impl
+func (e *Error) Unwrap() error {
+	return e.Err
 }
 
 // ---
@@ -40,81 +116,150 @@ func (s *Deserializer) Error() error {
 	return s.err
 }
 
-// Source stream
+// Source stream. Reading from the stream moves the offset of the deserializer
 func (s *Deserializer) Source() io.Reader {
-	return s.src
+	return &sourceReader{s}
+}
+
+// Offset of the next byte to read
+func (s *Deserializer) Offset() int64 {
+	return s.off
+}
+
+// Limit sets max size of a single field and max size of the whole data to read (zero is unlimited)
+func (s *Deserializer) Limit(maxFieldBytes uint32, maxTotalBytes int64) *Deserializer {
+	s.maxField = maxFieldBytes
+	s.maxTotal = maxTotalBytes
+	return s
+}
+
+// Strict makes the deserializer reject non-canonical encodings: non-zero bytes after a string terminating zero and a negative zero amount.
+// Such values can't be serialized back to the same bytes, so it matters where a digest is computed from re-serialized data
+func (s *Deserializer) Strict(strict bool) *Deserializer {
+	s.strict = strict
+	return s
+}
+
+// IsStrict gets the flag set by Strict
+func (s *Deserializer) IsStrict() bool {
+	return s.strict
+}
+
+// Limits gets max size of a single field and max size of the whole data to read, set by Limit (zero is unlimited)
+func (s *Deserializer) Limits() (maxFieldBytes uint32, maxTotalBytes int64) {
+	return s.maxField, s.maxTotal
+}
+
+// Fail sets an error for the current field (labeled by Field) or for the field just read, if there is no error yet
+func (s *Deserializer) Fail(err error) {
+	if s.err == nil && s.field == "" && s.last != "" {
+		s.err = &Error{Offset: s.lastOff, Field: s.label(s.last), Err: err}
+		return
+	}
+	s.fail(err)
+}
+
+// Prefix sets a prefix of field labels, like "TransferAsset" for "TransferAsset.Amount"
+func (s *Deserializer) Prefix(prefix string) *Deserializer {
+	s.prefix = prefix
+	return s
+}
+
+// LabelPrefix is the current prefix of field labels
+func (s *Deserializer) LabelPrefix() string {
+	return s.prefix
+}
+
+// Trace sets a function to call for every labeled field successfully read (nil to disable)
+func (s *Deserializer) Trace(fn func(Traced)) *Deserializer {
+	s.trace = fn
+	return s
+}
+
+// Field labels the next value to read, the label is used in errors, like: "TransferAsset.Amount at offset 74: unexpected EOF"
+func (s *Deserializer) Field(name string) *Deserializer {
+	s.field = name
+	s.fieldOff = s.off
+	return s
 }
 
 // ---
 
 // GetBytes ...
 func (s *Deserializer) GetBytes(n uint32) []byte {
-	if s.err == nil {
-		v := make([]byte, n)
-		cnt, err := s.src.Read(v)
-		if err == nil {
-			if uint32(cnt) == n {
-				return v
-			}
-			s.err = fmt.Errorf("didn't read specified amount of bytes, got %v, expected %v", cnt, n)
-		} else {
-			s.err = err
-		}
+	defer s.done()
+	v := s.read(n)
+	if v != nil && s.trace != nil {
+		s.traced(v)
 	}
-	return nil
+	return v
 }
 
 // GetByte ...
 func (s *Deserializer) GetByte() byte {
-	if s.err == nil {
-		b := s.GetBytes(1)
-		if b != nil {
-			return b[0]
+	defer s.done()
+	if b := s.fixed(1); b != nil {
+		if s.trace != nil {
+			s.traced(b[0])
 		}
+		return b[0]
 	}
 	return byte(0)
 }
 
 // GetUint16 ...
 func (s *Deserializer) GetUint16() uint16 {
-	if s.err == nil {
-		b := s.GetBytes(2)
-		if b != nil {
-			return uint16(s.shiftInt(b).Uint64() & 0xFFFF)
+	defer s.done()
+	if b := s.fixed(2); b != nil {
+		v := binary.LittleEndian.Uint16(b)
+		if s.trace != nil {
+			s.traced(v)
 		}
+		return v
 	}
 	return uint16(0)
 }
 
 // GetUint32 ...
 func (s *Deserializer) GetUint32() uint32 {
-	if s.err == nil {
-		b := s.GetBytes(4)
-		if b != nil {
-			return uint32(s.shiftInt(b).Uint64() & 0xFFFFFFFF)
+	defer s.done()
+	if b := s.fixed(4); b != nil {
+		v := binary.LittleEndian.Uint32(b)
+		if s.trace != nil {
+			s.traced(v)
 		}
+		return v
 	}
 	return uint32(0)
 }
 
 // GetUint64 ...
 func (s *Deserializer) GetUint64() uint64 {
-	if s.err == nil {
-		b := s.GetBytes(8)
-		if b != nil {
-			return s.shiftInt(b).Uint64()
+	defer s.done()
+	if b := s.fixed(8); b != nil {
+		v := binary.LittleEndian.Uint64(b)
+		if s.trace != nil {
+			s.traced(v)
 		}
+		return v
 	}
 	return uint64(0)
 }
 
 // GetUint256 ...
 func (s *Deserializer) GetUint256() *big.Int {
-	if s.err == nil {
-		b := s.GetBytes(32)
-		if b != nil {
-			return s.shiftInt(b)
+	defer s.done()
+	if b := s.fixed(32); b != nil {
+		// little endian => big endian
+		var be [32]byte
+		for i, v := range b {
+			be[31-i] = v
 		}
+		v := new(big.Int).SetBytes(be[:])
+		if s.trace != nil {
+			s.traced(v)
+		}
+		return v
 	}
 	return big.NewInt(0)
 }
@@ -123,18 +268,29 @@ func (s *Deserializer) GetUint256() *big.Int {
 func (s *Deserializer) GetString64() string {
 	const max = 64
 
-	if s.err == nil {
-		b := s.GetBytes(max)
-		if b != nil {
-			to := max
-			for i, v := range b {
-				if v == 0 {
-					to = i
-					break
+	defer s.done()
+	if b := s.fixed(max); b != nil {
+		to := max
+		for i, v := range b {
+			if v == 0 {
+				to = i
+				break
+			}
+		}
+		// the rest must be zero-padded in strict mode
+		if s.strict {
+			for _, v := range b[to:] {
+				if v != 0 {
+					s.fail(fmt.Errorf("string has non-zero bytes after terminating zero"))
+					return ""
 				}
 			}
-			return string(b[:to])
 		}
+		v := string(b[:to])
+		if s.trace != nil {
+			s.traced(v)
+		}
+		return v
 	}
 	return ""
 }
@@ -143,10 +299,11 @@ func (s *Deserializer) GetString64() string {
 func (s *Deserializer) GetPublicKey() mint.PublicKey {
 	var pub mint.PublicKey
 
-	if s.err == nil {
-		b := s.GetBytes(mint.PublicKeySize)
-		if b != nil {
-			copy(pub[:], b)
+	defer s.done()
+	if b := s.fixed(mint.PublicKeySize); b != nil {
+		copy(pub[:], b)
+		if s.trace != nil {
+			s.traced(pub)
 		}
 	}
 	return pub
@@ -156,10 +313,11 @@ func (s *Deserializer) GetPublicKey() mint.PublicKey {
 func (s *Deserializer) GetDigest() mint.Digest {
 	var d mint.Digest
 
-	if s.err == nil {
-		b := s.GetBytes(mint.DigestSize)
-		if b != nil {
-			copy(d[:], b)
+	defer s.done()
+	if b := s.fixed(mint.DigestSize); b != nil {
+		copy(d[:], b)
+		if s.trace != nil {
+			s.traced(d)
 		}
 	}
 	return d
@@ -169,10 +327,11 @@ func (s *Deserializer) GetDigest() mint.Digest {
 func (s *Deserializer) GetSignature() mint.Signature {
 	var sig mint.Signature
 
-	if s.err == nil {
-		b := s.GetBytes(mint.SignatureSize)
-		if b != nil {
-			copy(sig[:], b)
+	defer s.done()
+	if b := s.fixed(mint.SignatureSize); b != nil {
+		copy(sig[:], b)
+		if s.trace != nil {
+			s.traced(sig)
 		}
 	}
 	return sig
@@ -185,80 +344,229 @@ func (s *Deserializer) GetAmount() *amount.Amount {
 	const imax = 12
 	const fmax = 18
 
-	if s.err == nil {
+	defer s.done()
 
-		var err error
+	// sign, fraction and integer parts
+	b := s.fixed(1 + fmax/2 + imax/2)
+	if b == nil {
+		return nil
+	}
+	sign, fragPart, intPart := b[0], b[1:1+fmax/2], b[1+fmax/2:]
 
-		var sign = s.GetByte()
-		var fragPart = s.GetBytes(fmax / 2)
-		var intPart = s.GetBytes(imax / 2)
+	// check sign
+	if sign > 1 {
+		s.fail(fmt.Errorf("amount sign byte has invalid value: %v", sign))
+		return nil
+	}
 
-		// check sign
-		strSign := ""
-		if sign > 0 {
-			if sign > 1 {
-				s.err = fmt.Errorf("amount sign byte has invalid value: %v", sign)
-			}
-			strSign = "-"
-		}
+	// unflip parts
+	frag, err := unflipAmount(fragPart)
+	if err != nil {
+		s.fail(err)
+		return nil
+	}
+	integer, err := unflipAmount(intPart)
+	if err != nil {
+		s.fail(err)
+		return nil
+	}
 
-		// unflip frag part
-		strFrag := ""
-		if s.err == nil {
-			strFrag, err = unflipAmountString(fragPart)
-			if err != nil {
-				s.err = err
-			}
+	// integer * 10^precision + fraction
+	ret := amount.New()
+	ret.Value.SetUint64(integer)
+	ret.Value.Mul(ret.Value, precisionMul)
+	ret.Value.Add(ret.Value, new(big.Int).SetUint64(frag))
+	if sign == 1 {
+		if s.strict && ret.Value.Sign() == 0 {
+			s.fail(fmt.Errorf("amount is negative zero"))
+			return nil
 		}
+		ret.Value.Neg(ret.Value)
+	}
+	if s.trace != nil {
+		s.traced(ret)
+	}
+	return ret
+}
+
+// streamChunk is a size of a variable-length field read from a stream at once
+const streamChunk = 4096
 
-		// unflip int part
-		strInt := ""
-		if s.err == nil {
-			strInt, err = unflipAmountString(intPart)
-			if err != nil {
-				s.err = err
+// read reads exactly n bytes from the source
+func (s *Deserializer) read(n uint32) []byte {
+	if s.err != nil {
+		return nil
+	}
+	off := s.off
+	if s.maxField > 0 && n > s.maxField {
+		s.err = s.wrap(off, int(n), 0, &LimitError{Limit: LimitField, Max: int64(s.maxField), Got: int64(n)})
+		return nil
+	}
+	if s.maxTotal > 0 && off+int64(n) > s.maxTotal {
+		s.err = s.wrap(off, int(n), 0, &LimitError{Limit: LimitTotal, Max: s.maxTotal, Got: off + int64(n)})
+		return nil
+	}
+
+	// slice-backed
+	if s.slice {
+		remain := int64(len(s.buf)) - off
+		if int64(n) > remain {
+			s.off += remain
+			err := io.ErrUnexpectedEOF
+			if remain == 0 {
+				err = io.EOF
 			}
+			s.err = s.wrap(off, int(n), int(remain), err)
+			return nil
 		}
+		v := s.buf[off : off+int64(n) : off+int64(n)]
+		s.off += int64(n)
+		if !s.zeroCopy {
+			v = append([]byte(nil), v...)
+		}
+		return v
+	}
 
-		// try parse amount
-		if s.err == nil {
-			together := fmt.Sprintf("%v%v.%v", strSign, strInt, strFrag)
-			ret, err := amount.FromString(together)
-			if err != nil {
-				s.err = fmt.Errorf("failed to parse amount from `%v`: %v", together, err)
-			} else {
-				return ret
-			}
+	if n <= streamChunk {
+		return s.readInto(make([]byte, n))
+	}
 
+	// a declared size is not trusted: the buffer grows with the bytes actually read
+	buf := bytes.NewBuffer(make([]byte, 0, streamChunk))
+	cnt, err := io.CopyN(buf, s.src, int64(n))
+	s.off += cnt
+	if err != nil {
+		if err == io.EOF && cnt > 0 {
+			err = io.ErrUnexpectedEOF
 		}
+		s.err = s.wrap(off, int(n), int(cnt), err)
+		return nil
 	}
+	return buf.Bytes()
+}
 
-	return nil
+// fixed reads exactly n bytes (not more than scratch buffer size) without allocation.
+// Returned slice is valid until the next read
+func (s *Deserializer) fixed(n int) []byte {
+	if s.slice {
+		zc := s.zeroCopy
+		s.zeroCopy = true
+		v := s.read(uint32(n))
+		s.zeroCopy = zc
+		return v
+	}
+	if s.err != nil {
+		return nil
+	}
+	off := s.off
+	if s.maxTotal > 0 && off+int64(n) > s.maxTotal {
+		s.err = s.wrap(off, n, 0, &LimitError{Limit: LimitTotal, Max: s.maxTotal, Got: off + int64(n)})
+		return nil
+	}
+	return s.readInto(s.scratch[:n])
 }
 
-func (s *Deserializer) shiftInt(b []byte) *big.Int {
-	x := big.NewInt(0)
-	ret := big.NewInt(0)
-	for i, v := range b {
-		x = x.SetUint64(uint64(v) & 0xFF)
-		x = x.Lsh(x, uint(i)*8)
-		ret = ret.Or(ret, x)
+// readInto reads exactly len(v) bytes from the source stream
+func (s *Deserializer) readInto(v []byte) []byte {
+	off := s.off
+	cnt, err := io.ReadFull(s.src, v)
+	s.off += int64(cnt)
+	if err != nil {
+		s.err = s.wrap(off, len(v), cnt, err)
+		return nil
 	}
-	return ret
+	return v
 }
 
-// Convert some kind of a shit into string: [0x78 0x56 .. 0x34 0x12] => "1234...5678"
-func unflipAmountString(b []byte) (string, error) {
-	if b == nil || len(b) == 0 {
-		return "", fmt.Errorf("buffer is nil or empty")
+// fail sets an error for the current field
+func (s *Deserializer) fail(err error) {
+	if s.err == nil {
+		s.err = s.wrap(s.off, 0, 0, err)
 	}
+}
 
-	// reverse array
-	tmp := make([]byte, len(b))
-	for i := 0; i < len(b); i++ {
-		tmp[i] = b[len(b)-i-1]
+// wrap makes an error; offset of the labeled field is preferred
+func (s *Deserializer) wrap(off int64, expected, got int, err error) *Error {
+	if s.field != "" {
+		off = s.fieldOff
 	}
+	return &Error{
+		Offset:   off,
+		Field:    s.label(s.field),
+		Expected: expected,
+		Got:      got,
+		Err:      err,
+	}
+}
+
+// label of the field with the prefix
+func (s *Deserializer) label(field string) string {
+	if field == "" || s.prefix == "" {
+		return field
+	}
+	return s.prefix + "." + field
+}
+
+// traced passes the labeled field just read to the trace function
+func (s *Deserializer) traced(v interface{}) {
+	if s.field == "" {
+		return
+	}
+	s.trace(Traced{
+		Field:  s.label(s.field),
+		Offset: s.fieldOff,
+		Length: s.off - s.fieldOff,
+		Value:  v,
+	})
+}
+
+// done resets the current field label, keeping it as the last read one
+func (s *Deserializer) done() {
+	s.last, s.lastOff = s.field, s.fieldOff
+	s.field = ""
+}
+
+var precisionMul = new(big.Int).Exp(big.NewInt(10), big.NewInt(amount.Precision), nil)
 
-	// to the hex
-	return hex.EncodeToString(tmp), nil
+// Convert some kind of a shit into a number: [0x78 0x56 .. 0x34 0x12] => 1234...5678
+func unflipAmount(b []byte) (uint64, error) {
+	if len(b) == 0 {
+		return 0, fmt.Errorf("buffer is nil or empty")
+	}
+	var ret uint64
+	for i := len(b) - 1; i >= 0; i-- {
+		hi, lo := b[i]>>4, b[i]&0x0F
+		if hi > 9 || lo > 9 {
+			return 0, fmt.Errorf("failed to parse amount: invalid digits 0x%02x", b[i])
+		}
+		ret = ret*100 + uint64(hi)*10 + uint64(lo)
+	}
+	return ret, nil
+}
+
+// sourceReader reads the source stream of a deserializer keeping its offset
+type sourceReader struct {
+	s *Deserializer
+}
+
+func (r *sourceReader) Read(p []byte) (int, error) {
+	if r.s.slice {
+		if r.s.off >= int64(len(r.s.buf)) {
+			return 0, io.EOF
+		}
+	}
+	if r.s.maxTotal > 0 && r.s.off+int64(len(p)) > r.s.maxTotal {
+		if r.s.off >= r.s.maxTotal {
+			return 0, &LimitError{Limit: LimitTotal, Max: r.s.maxTotal, Got: r.s.off + int64(len(p))}
+		}
+		p = p[:r.s.maxTotal-r.s.off]
+	}
+	if r.s.slice {
+		n := copy(p, r.s.buf[r.s.off:])
+		r.s.off += int64(n)
+		return n, nil
+	}
+	n, err := r.s.src.Read(p)
+	r.s.off += int64(n)
+	return n, err
 }
@@ -1,8 +1,14 @@
 package serializer
 
 import (
+	"bytes"
 	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"runtime"
 	"testing"
+	"testing/iotest"
 
 	"github.com/void616/gm.mint/amount"
 )
@@ -104,3 +110,255 @@ func TestDerializer(t *testing.T) {
 		t.Fatal(des.Error())
 	}
 }
+
+func TestDeserializer_ShortReads(t *testing.T) {
+	var amo = amount.MustFromString("-987654321.102030405060708090")
+
+	ser := NewSerializer()
+	ser.PutUint64(0xDEADBEEF1337C0DE)
+	ser.PutString64("127.0.0.1")
+	ser.PutAmount(amo)
+	dat, err := ser.Data()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for name, r := range map[string]io.Reader{
+		"one byte": iotest.OneByteReader(bytes.NewReader(dat)),
+		"half":     iotest.HalfReader(bytes.NewReader(dat)),
+		"data err": iotest.DataErrReader(bytes.NewReader(dat)),
+	} {
+		t.Run(name, func(t *testing.T) {
+			des := NewStreamDeserializer(r)
+			if des.GetUint64() != 0xDEADBEEF1337C0DE {
+				t.Fatal(des.Error())
+			}
+			if des.GetString64() != "127.0.0.1" {
+				t.Fatal(des.Error())
+			}
+			if a := des.GetAmount(); a == nil || a.Value.Cmp(amo.Value) != 0 {
+				t.Fatal(des.Error())
+			}
+			if des.Offset() != int64(len(dat)) {
+				t.Fatalf("Offset() = %v, want %v", des.Offset(), len(dat))
+			}
+		})
+	}
+}
+
+func TestDeserializer_Error(t *testing.T) {
+	ser := NewSerializer()
+	ser.PutUint64(1)
+	ser.PutAmount(amount.MustFromString("1"))
+	dat, err := ser.Data()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// truncated amount
+	des := NewStreamDeserializer(iotest.OneByteReader(bytes.NewReader(dat[:len(dat)-3])))
+	des.Field("Tx.Nonce").GetUint64()
+	des.Field("Tx.Amount").GetAmount()
+
+	var derr *Error
+	if !errors.As(des.Error(), &derr) {
+		t.Fatalf("Error() = %v, want *Error", des.Error())
+	}
+	if derr.Offset != 8 || derr.Field != "Tx.Amount" || derr.Expected != 16 || derr.Got != 13 {
+		t.Fatalf("Error() = %#v", derr)
+	}
+	if !errors.Is(derr, io.ErrUnexpectedEOF) {
+		t.Fatalf("Error() = %v, want unexpected EOF", derr.Err)
+	}
+	if derr.Error() != "Tx.Amount at offset 8: unexpected EOF" {
+		t.Fatal(derr.Error())
+	}
+
+	// error is sticky
+	des.GetByte()
+	if des.Error() != derr {
+		t.Fatal("Error() is not sticky")
+	}
+
+	// unlabeled
+	des = NewDeserializer(dat[:4])
+	des.GetUint64()
+	if des.Error() == nil || des.Error().Error() != "offset 0: unexpected EOF" {
+		t.Fatal(des.Error())
+	}
+}
+
+func TestDeserializer_Limit(t *testing.T) {
+	dat := make([]byte, 100)
+
+	// field limit
+	des := NewDeserializer(dat)
+	des.Limit(10, 0)
+	if des.GetBytes(10) == nil {
+		t.Fatal(des.Error())
+	}
+	des.Field("Big").GetBytes(0xFFFFFFFF)
+	var lerr *LimitError
+	if !errors.As(des.Error(), &lerr) || lerr.Limit != LimitField || lerr.Got != 0xFFFFFFFF {
+		t.Fatalf("Error() = %v, want field limit error", des.Error())
+	}
+
+	// total limit
+	des = NewStreamDeserializer(bytes.NewReader(dat))
+	des.Limit(0, 50)
+	des.GetBytes(40)
+	if des.Error() != nil {
+		t.Fatal(des.Error())
+	}
+	des.GetBytes(11)
+	if !errors.As(des.Error(), &lerr) || lerr.Limit != LimitTotal || lerr.Max != 50 {
+		t.Fatalf("Error() = %v, want total limit error", des.Error())
+	}
+
+	// total limit on source stream
+	des = NewStreamDeserializer(bytes.NewReader(dat))
+	des.Limit(0, 50)
+	n, err := io.ReadFull(des.Source(), make([]byte, 60))
+	if n != 50 || !errors.As(err, &lerr) {
+		t.Fatalf("Source() read %v bytes, error = %v", n, err)
+	}
+
+	// fail a field just read
+	des = NewDeserializer(dat)
+	des.Field("Count").GetUint16()
+	des.Fail(&LimitError{Limit: LimitSigners, Max: 1, Got: 2})
+	if des.Error() == nil || des.Error().Error() != "Count at offset 0: signers limit exceeded, got 2, max 1" {
+		t.Fatal(des.Error())
+	}
+}
+
+func TestSliceDeserializer(t *testing.T) {
+	ser := NewSerializer()
+	ser.PutUint16(0xDEAD)
+	ser.PutBytes([]byte{1, 2, 3, 4})
+	ser.PutUint64(0xDEADBEEF1337C0DE)
+	ser.PutAmount(amount.MustFromString("-987654321.102030405060708090"))
+	dat, err := ser.Data()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// zero-copy
+	des := NewSliceDeserializer(dat)
+	des.GetUint16()
+	b := des.GetBytes(4)
+	if !bytes.Equal(b, []byte{1, 2, 3, 4}) || &b[0] != &dat[2] {
+		t.Fatal("GetBytes() is not a sub-slice")
+	}
+
+	// copying
+	des = NewDeserializer(dat)
+	des.GetUint16()
+	b = des.GetBytes(4)
+	if !bytes.Equal(b, []byte{1, 2, 3, 4}) || &b[0] == &dat[2] {
+		t.Fatal("GetBytes() is not a copy")
+	}
+
+	// source keeps the offset
+	var u64 [8]byte
+	if _, err := io.ReadFull(des.Source(), u64[:]); err != nil {
+		t.Fatal(err)
+	}
+	if des.Offset() != 14 || des.GetAmount().String() != "-987654321.102030405060708090" {
+		t.Fatal(des.Error())
+	}
+	if _, err := des.Source().Read(u64[:]); err != io.EOF {
+		t.Fatalf("Source() error = %v, want EOF", err)
+	}
+}
+
+func TestDeserializer_AmountDigits(t *testing.T) {
+	dat, _ := hex.DecodeString("00341200000000000000341200000000")
+	dat[3] = 0xAB
+	des := NewDeserializer(dat)
+	if des.GetAmount() != nil || des.Error() == nil {
+		t.Fatal("GetAmount() should fail on invalid digits")
+	}
+	dat[0] = 2
+	des = NewDeserializer(dat)
+	if des.GetAmount() != nil || des.Error() == nil {
+		t.Fatal("GetAmount() should fail on invalid sign")
+	}
+}
+
+func TestDeserializer_Trace(t *testing.T) {
+	ser := NewSerializer()
+	ser.PutUint16(7)
+	ser.PutUint32(3)
+	ser.PutBytes([]byte{1, 2, 3})
+	ser.PutAmount(amount.MustFromString("1.5"))
+	dat, _ := ser.Data()
+
+	var traced []Traced
+	des := NewDeserializer(dat).Prefix("Msg").Trace(func(f Traced) {
+		traced = append(traced, f)
+	})
+	des.Field("Code").GetUint16()
+	size := des.GetUint32() // unlabeled
+	des.Field("Data").GetBytes(size)
+	des.Field("Amount").GetAmount()
+	des.Field("Missing").GetByte()
+
+	want := []struct {
+		field  string
+		offset int64
+		length int64
+		value  string
+	}{
+		{"Msg.Code", 0, 2, "7"},
+		{"Msg.Data", 6, 3, "[1 2 3]"},
+		{"Msg.Amount", 9, 16, "1.500000000000000000"},
+	}
+	if len(traced) != len(want) {
+		t.Fatalf("Trace() got %v fields, want %v", len(traced), len(want))
+	}
+	for i, w := range want {
+		f := traced[i]
+		if f.Field != w.field || f.Offset != w.offset || f.Length != w.length || fmt.Sprint(f.Value) != w.value {
+			t.Errorf("Trace() field %v = %+v, want %+v", i, f, w)
+		}
+	}
+}
+
+func TestDeserializer_LargeField(t *testing.T) {
+	dat := make([]byte, 10000)
+	for i := range dat {
+		dat[i] = byte(i)
+	}
+
+	// read in chunks
+	des := NewStreamDeserializer(iotest.HalfReader(bytes.NewReader(dat)))
+	if v := des.GetBytes(uint32(len(dat))); !bytes.Equal(v, dat) {
+		t.Fatal(des.Error())
+	}
+
+	// truncated field of the max declared size doesn't allocate the declared size
+	var before, after runtime.MemStats
+	runtime.ReadMemStats(&before)
+	des = NewStreamDeserializer(bytes.NewReader(dat[:100]))
+	des.GetBytes(DefaultLimits.MaxFieldBytes)
+	runtime.ReadMemStats(&after)
+
+	var derr *Error
+	if !errors.As(des.Error(), &derr) || derr.Got != 100 || !errors.Is(derr, io.ErrUnexpectedEOF) {
+		t.Fatalf("Error() = %v", des.Error())
+	}
+	if des.Offset() != 100 {
+		t.Fatalf("Offset() = %v", des.Offset())
+	}
+	if alloc := after.TotalAlloc - before.TotalAlloc; alloc >= uint64(DefaultLimits.MaxFieldBytes)/4 {
+		t.Fatalf("allocated %v bytes", alloc)
+	}
+
+	// nothing to read
+	des = NewStreamDeserializer(bytes.NewReader(nil))
+	des.GetBytes(DefaultLimits.MaxFieldBytes)
+	if !errors.Is(des.Error(), io.EOF) {
+		t.Fatalf("Error() = %v", des.Error())
+	}
+}
@@ -0,0 +1,49 @@
+package serializer
+
+import "fmt"
+
+// Limits of decoding, zero value of a limit means no limit
+type Limits struct {
+	// MaxFieldBytes is a max size of a single field
+	MaxFieldBytes uint32
+	// MaxTransactionBytes is a max size of a transaction
+	MaxTransactionBytes int64
+	// MaxBlockBytes is a max size of a block
+	MaxBlockBytes int64
+	// MaxSigners is a max count of signers in a block
+	MaxSigners uint16
+	// MaxTransactions is a max count of transactions in a block
+	MaxTransactions uint16
+}
+
+// DefaultLimits are applied to new deserializers, transactions and blocks
+var DefaultLimits = Limits{
+	MaxFieldBytes:       1 << 20,
+	MaxTransactionBytes: 2 << 20,
+	MaxBlockBytes:       256 << 20,
+	MaxSigners:          1024,
+	// MaxTransactions is not limited, the header count (uint16) can't exceed 0xFFFF anyway
+}
+
+// Limit names
+const (
+	LimitField        = "field"
+	LimitTotal        = "total"
+	LimitSigners      = "signers"
+	LimitTransactions = "transactions"
+)
+
+// LimitError means a decoding limit is exceeded
+type LimitError struct {
+	// Limit name
+	Limit string
+	// Max value of the limit
+	Max int64
+	// Got value
+	Got int64
+}
+
+// Error impl
+func (e *LimitError) Error() string {
+	return fmt.Sprintf("%v limit exceeded, got %v, max %v", e.Limit, e.Got, e.Max)
+}
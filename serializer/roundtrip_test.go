@@ -0,0 +1,216 @@
+package serializer
+
+import (
+	"bytes"
+	"math/big"
+	"math/rand"
+	"reflect"
+	"strings"
+	"testing"
+	"testing/quick"
+
+	mint "github.com/void616/gm.mint"
+	"github.com/void616/gm.mint/amount"
+)
+
+// roundTrip checks that a value is decoded back and the data is re-encoded bit-exactly
+func roundTrip(t *testing.T, name string, put func(*Serializer), get func(*Deserializer) interface{}, want interface{}) bool {
+	ser := NewSerializer()
+	put(ser)
+	dat, err := ser.Data()
+	if err != nil {
+		t.Errorf("%v: Put error = %v", name, err)
+		return false
+	}
+	des := NewDeserializer(dat)
+	got := get(des)
+	if err := des.Error(); err != nil {
+		t.Errorf("%v: Get error = %v", name, err)
+		return false
+	}
+	if des.Offset() != int64(len(dat)) {
+		t.Errorf("%v: Get read %v bytes of %v", name, des.Offset(), len(dat))
+		return false
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("%v: Get = %v, want %v", name, got, want)
+		return false
+	}
+	return true
+}
+
+func TestRoundTrip(t *testing.T) {
+	cfg := &quick.Config{MaxCount: 500}
+
+	check := func(name string, f interface{}) {
+		if err := quick.Check(f, cfg); err != nil {
+			t.Errorf("%v: %v", name, err)
+		}
+	}
+
+	check("byte", func(v byte) bool {
+		return roundTrip(t, "byte", func(s *Serializer) { s.PutByte(v) }, func(d *Deserializer) interface{} { return d.GetByte() }, v)
+	})
+	check("uint16", func(v uint16) bool {
+		return roundTrip(t, "uint16", func(s *Serializer) { s.PutUint16(v) }, func(d *Deserializer) interface{} { return d.GetUint16() }, v)
+	})
+	check("uint32", func(v uint32) bool {
+		return roundTrip(t, "uint32", func(s *Serializer) { s.PutUint32(v) }, func(d *Deserializer) interface{} { return d.GetUint32() }, v)
+	})
+	check("uint64", func(v uint64) bool {
+		return roundTrip(t, "uint64", func(s *Serializer) { s.PutUint64(v) }, func(d *Deserializer) interface{} { return d.GetUint64() }, v)
+	})
+	check("uint256", func(v [32]byte) bool {
+		x := new(big.Int).SetBytes(v[:])
+		return roundTrip(t, "uint256", func(s *Serializer) { s.PutUint256(x) }, func(d *Deserializer) interface{} { return d.GetUint256() }, x)
+	})
+	check("bytes", func(v []byte) bool {
+		if v == nil {
+			v = []byte{}
+		}
+		return roundTrip(t, "bytes", func(s *Serializer) { s.PutBytes(v) }, func(d *Deserializer) interface{} { return append([]byte{}, d.GetBytes(uint32(len(v)))...) }, v)
+	})
+	check("string64", func(v string) bool {
+		v = strings.Replace(v, "\x00", "", -1)
+		if len(v) > 64 {
+			v = v[:64]
+		}
+		return roundTrip(t, "string64", func(s *Serializer) { s.PutString64(v) }, func(d *Deserializer) interface{} { return d.GetString64() }, v)
+	})
+	check("public key", func(v mint.PublicKey) bool {
+		return roundTrip(t, "public key", func(s *Serializer) { s.PutPublicKey(v) }, func(d *Deserializer) interface{} { return d.GetPublicKey() }, v)
+	})
+	check("digest", func(v mint.Digest) bool {
+		return roundTrip(t, "digest", func(s *Serializer) { s.PutDigest(v) }, func(d *Deserializer) interface{} { return d.GetDigest() }, v)
+	})
+	check("signature", func(v mint.Signature) bool {
+		return roundTrip(t, "signature", func(s *Serializer) { s.PutSignature(v) }, func(d *Deserializer) interface{} { return d.GetSignature() }, v)
+	})
+	check("amount", func(i uint64, f uint64, neg bool) bool {
+		a := amount.New()
+		a.Value.SetUint64(i % 1000000000000)
+		a.Value.Mul(a.Value, amount.FromInteger(1).Value)
+		a.Value.Add(a.Value, new(big.Int).SetUint64(f%1000000000000000000))
+		if neg {
+			a.Value.Neg(a.Value)
+		}
+		return roundTrip(t, "amount", func(s *Serializer) { s.PutAmount(a) }, func(d *Deserializer) interface{} { return d.GetAmount() }, a)
+	})
+}
+
+// TestReEncode checks that anything parseable in strict mode is re-encoded bit-exactly
+func TestReEncode(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+
+	tests := []struct {
+		name string
+		size int
+		gen  func([]byte)
+		get  func(*Deserializer, *Serializer)
+	}{
+		{
+			"uint256", 32, nil,
+			func(d *Deserializer, s *Serializer) { s.PutUint256(d.GetUint256()) },
+		},
+		{
+			"string64", 64,
+			// short strings with random tails
+			func(b []byte) { b[rnd.Intn(len(b))] = 0 },
+			func(d *Deserializer, s *Serializer) { s.PutString64(d.GetString64()) },
+		},
+		{
+			"amount", 16,
+			// decimal digits mostly
+			func(b []byte) {
+				b[0] = byte(rnd.Intn(3))
+				for i := 1; i < len(b); i++ {
+					if rnd.Intn(50) > 0 {
+						b[i] = byte(rnd.Intn(10)<<4 | rnd.Intn(10))
+					}
+				}
+			},
+			func(d *Deserializer, s *Serializer) {
+				if a := d.GetAmount(); a != nil {
+					s.PutAmount(a)
+				}
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			parsed := 0
+			for i := 0; i < 5000; i++ {
+				b := make([]byte, tt.size)
+				rnd.Read(b)
+				if tt.gen != nil {
+					tt.gen(b)
+				}
+				des := NewDeserializer(b).Strict(true)
+				ser := NewSerializer()
+				tt.get(des, ser)
+				if des.Error() != nil {
+					continue
+				}
+				parsed++
+				dat, err := ser.Data()
+				if err != nil {
+					t.Fatalf("%x: Put error = %v", b, err)
+				}
+				if !bytes.Equal(dat, b) {
+					t.Fatalf("%x: re-encoded as %x", b, dat)
+				}
+			}
+			if parsed == 0 {
+				t.Fatal("nothing parsed")
+			}
+		})
+	}
+}
+
+func TestDeserializer_Strict(t *testing.T) {
+	str := make([]byte, 64)
+	copy(str, "abc\x00def")
+	negZero := make([]byte, 16)
+	negZero[0] = 1
+
+	for strict, wantErr := range map[bool]bool{false: false, true: true} {
+		des := NewDeserializer(str).Strict(strict)
+		if v := des.GetString64(); (des.Error() != nil) != wantErr || (!wantErr && v != "abc") {
+			t.Fatalf("strict %v: GetString64() = %q, %v", strict, v, des.Error())
+		}
+		des = NewDeserializer(negZero).Strict(strict)
+		if v := des.GetAmount(); (des.Error() != nil) != wantErr || (!wantErr && v.Value.Sign() != 0) {
+			t.Fatalf("strict %v: GetAmount() = %v, %v", strict, v, des.Error())
+		}
+	}
+}
+
+func TestSerializer_StickyError(t *testing.T) {
+	s := NewSerializer()
+	s.PutUint256(nil)
+	_, want := s.Data()
+	s.PutString64(strings.Repeat("a", 65))
+	s.PutUint256(big.NewInt(-1))
+	s.PutString64("a\x00")
+	if _, err := s.Data(); err == nil || err.Error() != want.Error() {
+		t.Fatalf("error = %v, want %v", err, want)
+	}
+}
+
+func TestSerializer_PutUint256(t *testing.T) {
+	s := NewSerializer()
+	s.PutUint256(new(big.Int).Lsh(big.NewInt(1), 256))
+	if _, err := s.Data(); err == nil {
+		t.Fatal("Should fail on 257-bit value")
+	}
+	s = NewSerializer()
+	s.PutUint256(big.NewInt(-1))
+	if _, err := s.Data(); err == nil {
+		t.Fatal("Should fail on negative value")
+	}
+	s = NewSerializer()
+	s.PutUint256(big.NewInt(0x0102))
+	if h, _ := s.Hex(); h != "0201"+strings.Repeat("00", 30) {
+		t.Fatal(h)
+	}
+}
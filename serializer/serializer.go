@@ -4,6 +4,8 @@ import (
 	"bytes"
 	"encoding/hex"
 	"fmt"
+	"math/big"
+	"strings"
 
 	mint "github.com/void616/gm.mint"
 	"github.com/void616/gm.mint/amount"
@@ -44,6 +46,14 @@ func (s *Serializer) Hex() (string, error) {
 	return hex.EncodeToString(dat), nil
 }
 
+// Fail sets an error (if there is no error yet), so further calls do nothing
+func (s *Serializer) Fail(err error) *Serializer {
+	if s.err == nil {
+		s.err = err
+	}
+	return s
+}
+
 // ---
 
 // PutByte ...
@@ -124,11 +134,36 @@ func (s *Serializer) PutUint64(v uint64) *Serializer {
 	return s
 }
 
+// PutUint256 ...
+func (s *Serializer) PutUint256(v *big.Int) *Serializer {
+	if s.err != nil {
+		return s
+	}
+	if v == nil || v.Sign() < 0 || v.BitLen() > 256 {
+		s.err = fmt.Errorf("value is nil, negative or exceeds 256 bits")
+	}
+	if s.err == nil {
+		// big endian => little endian
+		var b [32]byte
+		be := v.Bytes()
+		for i, x := range be {
+			b[len(be)-i-1] = x
+		}
+		return s.PutBytes(b[:])
+	}
+	return s
+}
+
 // PutString64 ...
 func (s *Serializer) PutString64(v string) *Serializer {
 	const max = 64
+	if s.err != nil {
+		return s
+	}
 	if len(v) > max {
 		s.err = fmt.Errorf("string is too long, got %v, expected %v", len(v), max)
+	} else if strings.IndexByte(v, 0) >= 0 {
+		s.err = fmt.Errorf("string contains zero byte")
 	}
 	if s.err == nil {
 		b := make([]byte, max)
@@ -151,6 +186,16 @@ func (s *Serializer) PutPublicKey(v mint.PublicKey) *Serializer {
 	return s
 }
 
+// PutDigest ...
+func (s *Serializer) PutDigest(v mint.Digest) *Serializer {
+	return s.PutBytes(v[:])
+}
+
+// PutSignature ...
+func (s *Serializer) PutSignature(v mint.Signature) *Serializer {
+	return s.PutBytes(v[:])
+}
+
 // PutAmount ...
 func (s *Serializer) PutAmount(v *amount.Amount) *Serializer {
 
@@ -27,3 +27,12 @@ func StampToTime(timestamp uint64) time.Time {
 		Add(time.Second * secs).
 		Add(time.Microsecond * mcs)
 }
+
+// TimeToStamp converts time.Time to mint timestamp (microseconds), the time must not be before the epoch (year 1400)
+func TimeToStamp(t time.Time) uint64 {
+	secs := t.Unix() - epochStart.Unix()
+	if secs < 0 {
+		return 0
+	}
+	return uint64(secs)*1000000 + uint64(t.Nanosecond()/1000)
+}
@@ -25,3 +25,11 @@ func TestStampToTime(t *testing.T) {
 		})
 	}
 }
+
+func TestTimeToStamp(t *testing.T) {
+	for _, stamp := range []uint64{0, 19502164800000000, 19527035308000000, 19527219262123456} {
+		if got := TimeToStamp(StampToTime(stamp)); got != stamp {
+			t.Errorf("TimeToStamp() = %v, expected %v", got, stamp)
+		}
+	}
+}
@@ -0,0 +1,32 @@
+package transaction
+
+import (
+	"fmt"
+	"math/big"
+
+	mint "github.com/void616/gm.mint"
+	"github.com/void616/gm.mint/amount"
+)
+
+// AllocateTransferAsset splits `total` of `token` among `addresses` proportionally to `weights` (see amount.AllocateUnit)
+// and makes a transfer for every address with a non-zero part. Returned `dust` is the amount left undistributed
+func AllocateTransferAsset(token mint.Token, total, unit *amount.Amount, addresses []mint.PublicKey, weights []*big.Int) (txs []*TransferAsset, dust *amount.Amount, err error) {
+	if len(addresses) != len(weights) {
+		return nil, nil, fmt.Errorf("addresses count %v doesn't match weights count %v", len(addresses), len(weights))
+	}
+	parts, dust, err := amount.AllocateUnit(total, unit, weights...)
+	if err != nil {
+		return nil, nil, err
+	}
+	for i, p := range parts {
+		if p.Value.Sign() == 0 {
+			continue
+		}
+		txs = append(txs, &TransferAsset{
+			Address: addresses[i],
+			Token:   token,
+			Amount:  p,
+		})
+	}
+	return txs, dust, nil
+}
@@ -1,6 +1,7 @@
 package transaction
 
 import (
+	"encoding/json"
 	"fmt"
 )
 
@@ -58,3 +59,34 @@ func ValidCode(u uint16) bool {
 	_, ok := codeToString[Code(u)]
 	return ok
 }
+
+// MarshalJSON impl, the name of the code
+func (t Code) MarshalJSON() ([]byte, error) {
+	s := t.String()
+	if s == "" {
+		return nil, fmt.Errorf("unknown transaction code %v", uint16(t))
+	}
+	return json.Marshal(s)
+}
+
+// UnmarshalJSON impl, the name or the number of the code
+func (t *Code) UnmarshalJSON(b []byte) error {
+	var code uint16
+	if err := json.Unmarshal(b, &code); err == nil {
+		if !ValidCode(code) {
+			return fmt.Errorf("unknown transaction code %v", code)
+		}
+		*t = Code(code)
+		return nil
+	}
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		return err
+	}
+	x, err := ParseCode(s)
+	if err != nil {
+		return err
+	}
+	*t = x
+	return nil
+}
@@ -0,0 +1,261 @@
+package transaction
+
+import (
+	"fmt"
+	"io"
+	"reflect"
+	"sync"
+
+	mint "github.com/void616/gm.mint"
+	"github.com/void616/gm.mint/amount"
+	"github.com/void616/gm.mint/serializer"
+)
+
+// Transaction payload is described with struct tags, like `mint:"pubkey"`, untagged fields are skipped.
+// The payload goes after the nonce: the signer public key, then the fields in the order of declaration.
+// A transaction with another order implements wireOrder (see TransferAsset).
+// A new transaction type still needs its Code, Sign/Parse/Code methods (delegating to the codec) and a CodeToTransaction entry
+//
+// Kinds of the fields:
+//
+//	pubkey    - mint.PublicKey
+//	digest    - mint.Digest
+//	signature - mint.Signature
+//	amount    - *amount.Amount
+//	string64  - string, up to 64 bytes, zero-padded
+//	u32len    - []byte, prefixed with uint32 length
+//	token     - mint.Token, uint16
+//	wallettag - mint.WalletTag, uint8
+//	u8, u16, u32, u64 - unsigned integers
+const (
+	kindPublicKey = "pubkey"
+	kindDigest    = "digest"
+	kindSignature = "signature"
+	kindAmount    = "amount"
+	kindString64  = "string64"
+	kindBytes     = "u32len"
+	kindToken     = "token"
+	kindWalletTag = "wallettag"
+	kindUint8     = "u8"
+	kindUint16    = "u16"
+	kindUint32    = "u32"
+	kindUint64    = "u64"
+)
+
+// kindTypes are Go types allowed for the field kinds
+var kindTypes = map[string]reflect.Type{
+	kindPublicKey: reflect.TypeOf(mint.PublicKey{}),
+	kindDigest:    reflect.TypeOf(mint.Digest{}),
+	kindSignature: reflect.TypeOf(mint.Signature{}),
+	kindAmount:    reflect.TypeOf(&amount.Amount{}),
+	kindString64:  reflect.TypeOf(""),
+	kindBytes:     reflect.TypeOf([]byte{}),
+	kindToken:     reflect.TypeOf(mint.Token(0)),
+	kindWalletTag: reflect.TypeOf(mint.WalletTag(0)),
+	kindUint8:     reflect.TypeOf(uint8(0)),
+	kindUint16:    reflect.TypeOf(uint16(0)),
+	kindUint32:    reflect.TypeOf(uint32(0)),
+	kindUint64:    reflect.TypeOf(uint64(0)),
+}
+
+// layout of a transaction payload
+type layout struct {
+	// Name of the transaction type, prefixes field names in errors
+	name   string
+	fields []field
+}
+
+// field of a transaction payload
+type field struct {
+	name  string
+	index int
+	kind  string
+}
+
+// fieldFrom is the signer public key in the payload, it's not a struct field
+const fieldFrom = "From"
+
+// kindFrom is the kind of fieldFrom
+const kindFrom = "from"
+
+// wireOrder is implemented by a transaction which payload order differs from the default one
+type wireOrder interface {
+	// wireOrder returns names of all the tagged fields and fieldFrom in the order of encoding
+	wireOrder() []string
+}
+
+var layouts sync.Map // reflect.Type => *layout
+
+// layoutOf returns (cached) payload layout of a transaction struct (pointer)
+func layoutOf(v interface{}) (*layout, error) {
+	t := reflect.TypeOf(v)
+	if t == nil || t.Kind() != reflect.Ptr || t.Elem().Kind() != reflect.Struct {
+		return nil, fmt.Errorf("transaction %T must be a pointer to a struct", v)
+	}
+	t = t.Elem()
+	if l, ok := layouts.Load(t); ok {
+		return l.(*layout), nil
+	}
+
+	l := &layout{name: t.Name()}
+	fields := []field{{name: fieldFrom, index: -1, kind: kindFrom}}
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		kind, ok := f.Tag.Lookup("mint")
+		if !ok {
+			continue
+		}
+		typ, ok := kindTypes[kind]
+		if !ok {
+			return nil, fmt.Errorf("%v.%v: unknown field kind `%v`", l.name, f.Name, kind)
+		}
+		if f.Type != typ {
+			return nil, fmt.Errorf("%v.%v: field of kind `%v` must be %v, got %v", l.name, f.Name, kind, typ, f.Type)
+		}
+		if f.PkgPath != "" {
+			return nil, fmt.Errorf("%v.%v: field must be exported", l.name, f.Name)
+		}
+		fields = append(fields, field{name: f.Name, index: i, kind: kind})
+	}
+
+	if w, ok := v.(wireOrder); ok {
+		order := w.wireOrder()
+		if len(order) != len(fields) {
+			return nil, fmt.Errorf("%v: wire order has %v fields, expected %v", l.name, len(order), len(fields))
+		}
+		byName := make(map[string]field, len(fields))
+		for _, f := range fields {
+			byName[f.name] = f
+		}
+		for _, name := range order {
+			f, ok := byName[name]
+			if !ok {
+				return nil, fmt.Errorf("%v: wire order has unknown or repeated field `%v`", l.name, name)
+			}
+			delete(byName, name)
+			l.fields = append(l.fields, f)
+		}
+	} else {
+		l.fields = fields
+	}
+
+	actual, _ := layouts.LoadOrStore(t, l)
+	return actual.(*layout), nil
+}
+
+// encode puts transaction payload (except the nonce) into the serializer
+func encode(ser *serializer.Serializer, tx interface{}, from mint.PublicKey) {
+	l, err := layoutOf(tx)
+	if err != nil {
+		ser.Fail(err)
+		return
+	}
+	v := reflect.ValueOf(tx).Elem()
+	for _, f := range l.fields {
+		if f.kind == kindFrom {
+			ser.PutPublicKey(from)
+			continue
+		}
+		fv := v.Field(f.index)
+		switch f.kind {
+		case kindPublicKey:
+			ser.PutPublicKey(fv.Interface().(mint.PublicKey))
+		case kindDigest:
+			ser.PutDigest(fv.Interface().(mint.Digest))
+		case kindSignature:
+			ser.PutSignature(fv.Interface().(mint.Signature))
+		case kindAmount:
+			a := fv.Interface().(*amount.Amount)
+			if a == nil {
+				ser.Fail(fmt.Errorf("%v.%v is nil", l.name, f.name))
+				return
+			}
+			ser.PutAmount(a)
+		case kindString64:
+			ser.PutString64(fv.String())
+		case kindBytes:
+			b := fv.Bytes()
+			ser.PutUint32(uint32(len(b)))
+			ser.PutBytes(b)
+		case kindToken, kindUint16:
+			ser.PutUint16(uint16(fv.Uint()))
+		case kindWalletTag, kindUint8:
+			ser.PutByte(uint8(fv.Uint()))
+		case kindUint32:
+			ser.PutUint32(uint32(fv.Uint()))
+		case kindUint64:
+			ser.PutUint64(fv.Uint())
+		}
+	}
+}
+
+// decode gets transaction payload (except the nonce) from the deserializer and returns signer public key.
+// Fields are labeled with their names, errors are kept in the deserializer
+func decode(des *serializer.Deserializer, tx interface{}) (from mint.PublicKey) {
+	l, err := layoutOf(tx)
+	if err != nil {
+		des.Fail(err)
+		return
+	}
+	v := reflect.ValueOf(tx).Elem()
+	for _, f := range l.fields {
+		if des.Error() != nil {
+			return
+		}
+		if f.kind == kindFrom {
+			from = des.Field(f.name).GetPublicKey()
+			continue
+		}
+		fv := v.Field(f.index)
+		switch f.kind {
+		case kindPublicKey:
+			fv.Set(reflect.ValueOf(des.Field(f.name).GetPublicKey()))
+		case kindDigest:
+			fv.Set(reflect.ValueOf(des.Field(f.name).GetDigest()))
+		case kindSignature:
+			fv.Set(reflect.ValueOf(des.Field(f.name).GetSignature()))
+		case kindAmount:
+			fv.Set(reflect.ValueOf(des.Field(f.name).GetAmount()))
+		case kindString64:
+			fv.SetString(des.Field(f.name).GetString64())
+		case kindBytes:
+			size := des.Field(f.name + "Size").GetUint32()
+			fv.SetBytes(des.Field(f.name).GetBytes(size))
+		case kindToken:
+			code := des.Field(f.name).GetUint16()
+			if des.Error() == nil && !mint.ValidToken(code) {
+				des.Fail(fmt.Errorf("unknown token with code `%v`", code))
+			}
+			fv.SetUint(uint64(code))
+		case kindWalletTag:
+			code := des.Field(f.name).GetByte()
+			if des.Error() == nil && !mint.ValidWalletTag(code) {
+				des.Fail(fmt.Errorf("unknown wallet tag with code `%v`", code))
+			}
+			fv.SetUint(uint64(code))
+		case kindUint8:
+			fv.SetUint(uint64(des.Field(f.name).GetByte()))
+		case kindUint16:
+			fv.SetUint(uint64(des.Field(f.name).GetUint16()))
+		case kindUint32:
+			fv.SetUint(uint64(des.Field(f.name).GetUint32()))
+		case kindUint64:
+			fv.SetUint(des.Field(f.name).GetUint64())
+		}
+	}
+	return
+}
+
+// parse parses a transaction data into the transaction struct
+func parse(tx interface{}, r io.Reader, limits serializer.Limits) (*ParsedTransaction, error) {
+	l, err := layoutOf(tx)
+	if err != nil {
+		return nil, err
+	}
+	pars, err := newParser(r, l.name, limits)
+	if err != nil {
+		return nil, err
+	}
+	from := decode(pars.Deserializer, tx)
+	return pars.Complete(from)
+}
@@ -0,0 +1,194 @@
+package transaction
+
+import (
+	"bytes"
+	"encoding/hex"
+	"reflect"
+	"strings"
+	"testing"
+
+	mint "github.com/void616/gm.mint"
+	"github.com/void616/gm.mint/amount"
+	"github.com/void616/gm.mint/serializer"
+	"github.com/void616/gm.mint/signer"
+)
+
+func TestCodec_Golden(t *testing.T) {
+	var pk, from mint.PublicKey
+	for i := range pk {
+		pk[i] = byte(i + 1)
+		from[i] = byte(0xA0 + i)
+	}
+
+	// encoded by the former hand-written constructors
+	tests := []struct {
+		tx   Transactioner
+		want string
+	}{
+		{&RegisterNode{NodeAddress: pk, NodeIP: "127.0.0.1"}, "2a00000000000000a0a1a2a3a4a5a6a7a8a9aaabacadaeafb0b1b2b3b4b5b6b7b8b9babbbcbdbebf0102030405060708090a0b0c0d0e0f101112131415161718191a1b1c1d1e1f203132372e302e302e310000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000ea0bd9a465dab2e83f6e2c099a399f4f4b7844d2e654ced99394a24657e60c01"},
+		{&UnregisterNode{NodeAddress: pk}, "2a00000000000000a0a1a2a3a4a5a6a7a8a9aaabacadaeafb0b1b2b3b4b5b6b7b8b9babbbcbdbebf0102030405060708090a0b0c0d0e0f101112131415161718191a1b1c1d1e1f2000f16fe8e287173b7531ff26b79c05ddaf943987744c2b0c563d1b45432450bcdb"},
+		{&TransferAsset{Address: pk, Token: mint.TokenGOLD, Amount: amount.MustFromString("1.666")}, "2a000000000000000100a0a1a2a3a4a5a6a7a8a9aaabacadaeafb0b1b2b3b4b5b6b7b8b9babbbcbdbebf0102030405060708090a0b0c0d0e0f101112131415161718191a1b1c1d1e1f2000000000000000006066010000000000000139ec2bdc35b5e20967e721b5fb98e0207489e6d69d7fde99034bbfbd306ecb"},
+		{&UserData{Data: []byte{0xDE, 0xAD, 0xBE, 0xEF}}, "2a00000000000000a0a1a2a3a4a5a6a7a8a9aaabacadaeafb0b1b2b3b4b5b6b7b8b9babbbcbdbebf04000000deadbeef00dbda70f57f005c04fa3f4f0d7dbc1e22b139d89fe154e89c1d9db948e495d3ed"},
+		{&SetWalletTag{Address: pk, Tag: mint.WalletTagSupervisor}, "2a00000000000000a0a1a2a3a4a5a6a7a8a9aaabacadaeafb0b1b2b3b4b5b6b7b8b9babbbcbdbebf0102030405060708090a0b0c0d0e0f101112131415161718191a1b1c1d1e1f2003007d8c6e556d796de670dcaa7d9667ebf05d2b2c639d0a4f31ca0fe0b6b8de3da9"},
+		{&UnsetWalletTag{Address: pk, Tag: mint.WalletTagEmission}, "2a00000000000000a0a1a2a3a4a5a6a7a8a9aaabacadaeafb0b1b2b3b4b5b6b7b8b9babbbcbdbebf0102030405060708090a0b0c0d0e0f101112131415161718191a1b1c1d1e1f20050064aedc86cb8cfd8911f3d9a56edd04699a53c2993eb4607e861d2ac2aa750875"},
+		{&DistributionFee{OwnerAddress: pk, AmountMNT: amount.MustFromString("1.666"), AmountGOLD: amount.MustFromString("-666.1")}, "2a00000000000000a0a1a2a3a4a5a6a7a8a9aaabacadaeafb0b1b2b3b4b5b6b7b8b9babbbcbdbebf0102030405060708090a0b0c0d0e0f101112131415161718191a1b1c1d1e1f200000000000000000606601000000000001000000000000000010660600000000007a20db25a16fdd67d80fe5e3a5b98dbbc38fbf69844c16f3b3fb436789fbb541"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.tx.Code().String(), func(t *testing.T) {
+			u, err := Unsigned(tt.tx, from, 42)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := hex.EncodeToString(u.Data); got != tt.want {
+				t.Fatalf("Unsigned() = %v, want %v", got, tt.want)
+			}
+
+			parsed := reflect.New(reflect.TypeOf(tt.tx).Elem()).Interface().(Transactioner)
+			ptx, err := parsed.Parse(bytes.NewReader(u.Data))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if ptx.From != from || ptx.Nonce != 42 || !reflect.DeepEqual(parsed, tt.tx) {
+				t.Fatalf("Parse() = %#v, want %#v", parsed, tt.tx)
+			}
+		})
+	}
+}
+
+// testMessage is a struct-only message using all the field kinds
+type testMessage struct {
+	A    uint8  `mint:"u8"`
+	B    uint16 `mint:"u16"`
+	C    uint32 `mint:"u32"`
+	D    uint64 `mint:"u64"`
+	Skip int
+	Key  mint.PublicKey `mint:"pubkey"`
+	Dig  mint.Digest    `mint:"digest"`
+	Sig  mint.Signature `mint:"signature"`
+	Amt  *amount.Amount `mint:"amount"`
+	Str  string         `mint:"string64"`
+	Data []byte         `mint:"u32len"`
+	Tok  mint.Token     `mint:"token"`
+	Tag  mint.WalletTag `mint:"wallettag"`
+}
+
+// testOrdered is encoded in reverse order with the signer in the middle
+type testOrdered struct {
+	A uint8  `mint:"u8"`
+	B uint16 `mint:"u16"`
+}
+
+func (t *testOrdered) wireOrder() []string { return []string{"B", fieldFrom, "A"} }
+
+// testBadOrder misses a field in the wire order
+type testBadOrder testOrdered
+
+func (t *testBadOrder) wireOrder() []string { return []string{"B", fieldFrom, "B"} }
+
+func TestCodec_WireOrder(t *testing.T) {
+	from := mint.PublicKey{0xFF}
+	u, err := construct(&testOrdered{A: 1, B: 2}, from, 7).Unsigned()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "0700000000000000" + "0200" + hex.EncodeToString(from[:]) + "01"; hex.EncodeToString(u.Payload) != want {
+		t.Fatalf("Unsigned() payload = %x, want %v", u.Payload, want)
+	}
+	parsed := &testOrdered{}
+	ptx, err := parse(parsed, bytes.NewReader(u.Data), serializer.DefaultLimits)
+	if err != nil || ptx.From != from || parsed.A != 1 || parsed.B != 2 {
+		t.Fatalf("parse() = %+v, %v", parsed, err)
+	}
+}
+
+func TestCodec_Kinds(t *testing.T) {
+	var from mint.PublicKey
+	from[0] = 0xFF
+	msg := &testMessage{
+		A: 1, B: 2, C: 3, D: 4, Skip: 5,
+		Amt:  amount.MustFromString("-0.5"),
+		Str:  "hello",
+		Data: []byte{1, 2, 3},
+		Tok:  mint.TokenMNT,
+		Tag:  mint.WalletTagOwner,
+	}
+	msg.Key[1], msg.Dig[2], msg.Sig[3] = 1, 2, 3
+
+	u, err := construct(msg, from, 7).Unsigned()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := 8 + 32 + 1 + 2 + 4 + 8 + 32 + 32 + 64 + 16 + 64 + 4 + 3 + 2 + 1
+	if len(u.Payload) != want {
+		t.Fatalf("Unsigned() payload size = %v, want %v", len(u.Payload), want)
+	}
+
+	parsed := &testMessage{}
+	ptx, err := parse(parsed, bytes.NewReader(u.Data), serializer.DefaultLimits)
+	if err != nil {
+		t.Fatal(err)
+	}
+	msg.Skip = 0
+	if ptx.From != from || !reflect.DeepEqual(parsed, msg) {
+		t.Fatalf("parse() = %#v, want %#v", parsed, msg)
+	}
+}
+
+func TestCodec_Errors(t *testing.T) {
+	type badKind struct {
+		A uint8 `mint:"u7"`
+	}
+	type badType struct {
+		A int `mint:"u64"`
+	}
+	type unexported struct {
+		a uint8 `mint:"u8"`
+	}
+	for _, v := range []interface{}{&badKind{}, &badType{}, &unexported{}, &testBadOrder{}, badKind{}} {
+		if _, err := layoutOf(v); err == nil {
+			t.Errorf("layoutOf(%T) should fail", v)
+		}
+	}
+
+	if _, err := Unsigned(&TransferAsset{Token: mint.TokenGOLD}, mint.PublicKey{}, 0); err == nil || err.Error() != "TransferAsset.Amount is nil" {
+		t.Errorf("Unsigned() error = %v", err)
+	}
+
+	// unknown token
+	u, err := Unsigned(&TransferAsset{Token: 9, Amount: amount.New()}, mint.PublicKey{}, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = (&TransferAsset{}).Parse(bytes.NewReader(u.Data))
+	if err == nil || !strings.HasPrefix(err.Error(), "TransferAsset.Token at offset 8: unknown token") {
+		t.Errorf("Parse() error = %v", err)
+	}
+}
+
+func TestParseDeserializer(t *testing.T) {
+	s, _ := signer.New()
+	tx := &SetWalletTag{Address: s.PublicKey(), Tag: mint.WalletTagOwner}
+	signed, err := tx.Sign(s, 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	parsed := &SetWalletTag{}
+	des := serializer.NewDeserializer(signed.Data).Prefix("Outer")
+	ptx, err := ParseDeserializer(parsed, des)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ptx.Digest != signed.Digest || ptx.Signature != signed.Signature || ptx.Nonce != 5 || !reflect.DeepEqual(parsed, tx) {
+		t.Fatalf("ParseDeserializer() = %+v, %#v", ptx, parsed)
+	}
+	if des.LabelPrefix() != "Outer" || des.Offset() != int64(len(signed.Data)) {
+		t.Fatal("ParseDeserializer() should restore the prefix and read the whole data")
+	}
+
+	// labels are nested
+	_, err = ParseDeserializer(&SetWalletTag{}, serializer.NewDeserializer(signed.Data[:50]).Prefix("Outer"))
+	if err == nil || !strings.HasPrefix(err.Error(), "Outer.SetWalletTag.Address at offset 40") {
+		t.Fatalf("ParseDeserializer() error = %v", err)
+	}
+}
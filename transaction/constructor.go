@@ -19,6 +19,20 @@ type SignedTransaction struct {
 	Signature mint.Signature
 }
 
+// UnsignedTransaction data
+type UnsignedTransaction struct {
+	Digest  mint.Digest
+	Payload []byte
+	Data    []byte
+}
+
+// construct makes a constructor with transaction payload (see codec)
+func construct(tx interface{}, from mint.PublicKey, nonce uint64) *constructor {
+	ctor := newConstructor(nonce)
+	encode(ctor.Serializer, tx, from)
+	return ctor
+}
+
 func newConstructor(nonce uint64) *constructor {
 	c := &constructor{
 		Serializer: serializer.NewSerializer(),
@@ -39,15 +53,9 @@ func (c *constructor) Sign(signer *signer.Signer) (*SignedTransaction, error) {
 	}
 
 	// make payload digest
-	var txdigest mint.Digest
-	{
-		hasher := sha3.New256()
-		_, err = hasher.Write(payload)
-		if err != nil {
-			return nil, err
-		}
-		digest := hasher.Sum(nil)
-		copy(txdigest[:], digest)
+	txdigest, err := payloadDigest(payload)
+	if err != nil {
+		return nil, err
 	}
 
 	// sign digest
@@ -69,3 +77,84 @@ func (c *constructor) Sign(signer *signer.Signer) (*SignedTransaction, error) {
 		Signature: txsignature,
 	}, nil
 }
+
+// Unsigned completes transaction data without a signature: "signed bit" is zero and followed by payload digest
+func (c *constructor) Unsigned() (*UnsignedTransaction, error) {
+	// get payload
+	payload, err := c.Data()
+	if err != nil {
+		return nil, err
+	}
+
+	// make payload digest
+	txdigest, err := payloadDigest(payload)
+	if err != nil {
+		return nil, err
+	}
+
+	c.PutByte(0)            // append a byte - "signed bit"
+	c.PutBytes(txdigest[:]) // digest
+
+	// data
+	txdata, err := c.Data()
+	if err != nil {
+		return nil, err
+	}
+
+	return &UnsignedTransaction{
+		Digest:  txdigest,
+		Payload: payload,
+		Data:    txdata,
+	}, nil
+}
+
+// Unsigned constructs transaction data of the sender `from` without signing it
+func Unsigned(tx Transactioner, from mint.PublicKey, nonce uint64) (*UnsignedTransaction, error) {
+	return construct(tx, from, nonce).Unsigned()
+}
+
+// Signed completes the transaction with the signature of the payload digest made elsewhere (like by a hardware signer).
+// The signature is not verified
+func (t *UnsignedTransaction) Signed(signature mint.Signature) *SignedTransaction {
+	data := make([]byte, 0, len(t.Payload)+1+mint.SignatureSize)
+	data = append(data, t.Payload...)
+	data = append(data, 1) // "signed bit"
+	data = append(data, signature[:]...)
+	return &SignedTransaction{
+		Digest:    t.Digest,
+		Data:      data,
+		Signature: signature,
+	}
+}
+
+// PayloadSize is a size in bytes of the transaction payload (the data to be signed)
+func PayloadSize(tx Transactioner) (int, error) {
+	u, err := Unsigned(tx, mint.PublicKey{}, 0)
+	if err != nil {
+		return 0, err
+	}
+	return len(u.Payload), nil
+}
+
+// Size is a size in bytes of the whole transaction data, signed or not
+func Size(tx Transactioner, signed bool) (int, error) {
+	n, err := PayloadSize(tx)
+	if err != nil {
+		return 0, err
+	}
+	if signed {
+		return n + 1 + mint.SignatureSize, nil
+	}
+	return n + 1 + mint.DigestSize, nil
+}
+
+// payloadDigest is SHA3-256 of the transaction payload
+func payloadDigest(payload []byte) (mint.Digest, error) {
+	var d mint.Digest
+	hasher := sha3.New256()
+	if _, err := hasher.Write(payload); err != nil {
+		return d, err
+	}
+	copy(d[:], hasher.Sum(nil))
+	return d, nil
+}
@@ -0,0 +1,89 @@
+package transaction
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+
+	mint "github.com/void616/gm.mint"
+	"github.com/void616/gm.mint/amount"
+	"github.com/void616/gm.mint/signer"
+)
+
+func TestSize(t *testing.T) {
+
+	dst, _ := signer.New()
+	signer, _ := signer.New()
+
+	txs := []Transactioner{
+		&RegisterNode{NodeAddress: signer.PublicKey(), NodeIP: "127.0.0.1"},
+		&UnregisterNode{NodeAddress: signer.PublicKey()},
+		&TransferAsset{Address: dst.PublicKey(), Token: mint.TokenGOLD, Amount: amount.MustFromString("1.666")},
+		&UserData{Data: []byte{0xDE, 0xAD, 0xBE, 0xEF}},
+		&SetWalletTag{Address: signer.PublicKey(), Tag: mint.WalletTagSupervisor},
+		&UnsetWalletTag{Address: signer.PublicKey(), Tag: mint.WalletTagEmission},
+		&DistributionFee{OwnerAddress: signer.PublicKey(), AmountMNT: amount.MustFromString("1.666"), AmountGOLD: amount.MustFromString("666.1")},
+	}
+
+	for _, tx := range txs {
+		t.Run(tx.Code().String(), func(t *testing.T) {
+			signed, err := tx.Sign(signer, 42)
+			if err != nil {
+				t.Fatal(err)
+			}
+			size, err := Size(tx, true)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if size != len(signed.Data) {
+				t.Errorf("Size() signed = %v, want %v", size, len(signed.Data))
+			}
+
+			unsigned, err := Unsigned(tx, signer.PublicKey(), 42)
+			if err != nil {
+				t.Fatal(err)
+			}
+			size, err = Size(tx, false)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if size != len(unsigned.Data) {
+				t.Errorf("Size() unsigned = %v, want %v", size, len(unsigned.Data))
+			}
+			if unsigned.Digest != signed.Digest || !bytes.Equal(unsigned.Payload, signed.Data[:len(unsigned.Payload)]) {
+				t.Error("Unsigned() payload differs from the signed one")
+			}
+
+			// unsigned transaction is parseable
+			parsed, err := CodeToTransaction(tx.Code())
+			if err != nil {
+				t.Fatal(err)
+			}
+			ptx, err := parsed.Parse(bytes.NewBuffer(unsigned.Data))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if ptx.Digest != unsigned.Digest || ptx.From != signer.PublicKey() {
+				t.Error("Parsed unsigned transaction differs")
+			}
+		})
+	}
+}
+
+func TestUnsignedTransaction_Signed(t *testing.T) {
+	s, _ := signer.New()
+	tx := &UserData{Data: []byte{0xDE, 0xAD, 0xBE, 0xEF}}
+
+	want, err := tx.Sign(s, 42)
+	if err != nil {
+		t.Fatal(err)
+	}
+	unsigned, err := Unsigned(tx, s.PublicKey(), 42)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := unsigned.Signed(s.Sign(unsigned.Digest[:]))
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("Signed() = %x, want %x", got.Data, want.Data)
+	}
+}
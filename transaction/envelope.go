@@ -0,0 +1,123 @@
+package transaction
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/void616/gm.mint/serializer"
+	"github.com/void616/gm.mint/signer"
+)
+
+// Envelope is a transaction data (signed or unsigned) with its code.
+// Encoded envelope is the code (uint16) followed by the data, exactly as a transaction inside a block
+type Envelope struct {
+	Code Code
+	Data []byte
+}
+
+// Seal signs the transaction and puts it into an envelope
+func Seal(tx Transactioner, signer *signer.Signer, nonce uint64) (*Envelope, *SignedTransaction, error) {
+	signed, err := tx.Sign(signer, nonce)
+	if err != nil {
+		return nil, nil, err
+	}
+	return &Envelope{Code: tx.Code(), Data: signed.Data}, signed, nil
+}
+
+// Encode envelope into bytes
+func (e *Envelope) Encode() ([]byte, error) {
+	if !ValidCode(uint16(e.Code)) {
+		return nil, fmt.Errorf("unknown transaction code %v", uint16(e.Code))
+	}
+	ser := serializer.NewSerializer()
+	ser.PutUint16(uint16(e.Code)) // code
+	ser.PutBytes(e.Data)          // transaction
+	return ser.Data()
+}
+
+// Open parses transaction data of the envelope, trailing bytes are not allowed
+func (e *Envelope) Open() (Transactioner, *ParsedTransaction, error) {
+	tx, err := CodeToTransaction(e.Code)
+	if err != nil {
+		return nil, nil, err
+	}
+	des := serializer.NewDeserializer(e.Data)
+	ptx, err := ParseDeserializer(tx, des)
+	if err != nil {
+		return nil, nil, err
+	}
+	if n := len(e.Data) - int(des.Offset()); n != 0 {
+		return nil, nil, fmt.Errorf("transaction data has %v trailing bytes", n)
+	}
+	return tx, ptx, nil
+}
+
+// DecodeEnvelope decodes envelope bytes and parses the transaction, trailing bytes are not allowed
+func DecodeEnvelope(b []byte) (*Envelope, Transactioner, *ParsedTransaction, error) {
+	r := bytes.NewReader(b)
+	e, tx, ptx, err := ReadEnvelope(r)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	if r.Len() != 0 {
+		return nil, nil, nil, fmt.Errorf("envelope has %v trailing bytes", r.Len())
+	}
+	return e, tx, ptx, nil
+}
+
+// ReadEnvelope reads an envelope from the stream: the code and exactly one transaction data
+func ReadEnvelope(r io.Reader) (*Envelope, Transactioner, *ParsedTransaction, error) {
+	des := serializer.NewStreamDeserializer(r)
+	code := des.Field("Code").GetUint16()
+	if err := des.Error(); err != nil {
+		return nil, nil, nil, err
+	}
+	if !ValidCode(code) {
+		return nil, nil, nil, fmt.Errorf("unknown transaction code %v", code)
+	}
+	return ReadEnvelopeData(Code(code), r)
+}
+
+// ReadEnvelopeData reads transaction data of the code from the stream, when the code is already read (like inside a block)
+func ReadEnvelopeData(code Code, r io.Reader) (*Envelope, Transactioner, *ParsedTransaction, error) {
+	return ReadEnvelopeDataWithLimits(code, r, serializer.DefaultLimits)
+}
+
+// ReadEnvelopeDataWithLimits reads transaction data of the code from the stream with specified decoding limits (see ParseWithLimits)
+func ReadEnvelopeDataWithLimits(code Code, r io.Reader, limits serializer.Limits) (*Envelope, Transactioner, *ParsedTransaction, error) {
+	tx, err := CodeToTransaction(code)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	data := &bytes.Buffer{}
+	ptx, err := ParseWithLimits(tx, io.TeeReader(r, data), limits)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	return &Envelope{Code: code, Data: data.Bytes()}, tx, ptx, nil
+}
+
+// MarshalJSON impl, canonical JSON form of the transaction (see JSON)
+func (e *Envelope) MarshalJSON() ([]byte, error) {
+	j, err := ToJSON(e.Code, e.Data)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(j)
+}
+
+// UnmarshalJSON impl, canonical JSON form of the transaction (see JSON)
+func (e *Envelope) UnmarshalJSON(b []byte) error {
+	j := &JSON{}
+	if err := json.Unmarshal(b, j); err != nil {
+		return err
+	}
+	data, err := j.Data()
+	if err != nil {
+		return err
+	}
+	*e = Envelope{Code: j.Body.Code(), Data: data}
+	return nil
+}
@@ -0,0 +1,94 @@
+package transaction
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+
+	mint "github.com/void616/gm.mint"
+	"github.com/void616/gm.mint/amount"
+	"github.com/void616/gm.mint/serializer"
+	"github.com/void616/gm.mint/signer"
+)
+
+func TestEnvelope(t *testing.T) {
+	s, _ := signer.New()
+	dst, _ := signer.New()
+	tx := &TransferAsset{Address: dst.PublicKey(), Token: mint.TokenMNT, Amount: amount.MustFromString("3")}
+
+	e, signed, err := Seal(tx, s, 9)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := e.Encode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(b[:2], []byte{byte(TransferAssetTx), 0}) || !bytes.Equal(b[2:], signed.Data) {
+		t.Fatalf("Encode() = %x", b)
+	}
+
+	// decode
+	e2, tx2, ptx, err := DecodeEnvelope(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(e, e2) || !reflect.DeepEqual(tx, tx2) || ptx.Digest != signed.Digest || ptx.Nonce != 9 || ptx.From != s.PublicKey() {
+		t.Fatalf("DecodeEnvelope() = %+v, %+v, %+v", e2, tx2, ptx)
+	}
+	tx3, ptx3, err := e.Open()
+	if err != nil || !reflect.DeepEqual(tx, tx3) || !reflect.DeepEqual(ptx, ptx3) {
+		t.Fatalf("Open() = %+v, %+v, %v", tx3, ptx3, err)
+	}
+
+	// stream of envelopes
+	stream := bytes.NewReader(append(append([]byte{}, b...), b...))
+	for i := 0; i < 2; i++ {
+		if e3, _, _, err := ReadEnvelope(stream); err != nil || !reflect.DeepEqual(e, e3) {
+			t.Fatalf("ReadEnvelope() = %+v, %v", e3, err)
+		}
+	}
+	if stream.Len() != 0 {
+		t.Fatal("ReadEnvelope() should read exactly one envelope")
+	}
+
+	// json
+	j, err := json.Marshal(e)
+	if err != nil {
+		t.Fatal(err)
+	}
+	e4 := &Envelope{}
+	if err := json.Unmarshal(j, e4); err != nil || !reflect.DeepEqual(e, e4) {
+		t.Fatalf("UnmarshalJSON() = %+v, %v", e4, err)
+	}
+
+	// errors
+	if _, _, _, err := DecodeEnvelope(append(b, 0)); err == nil {
+		t.Error("DecodeEnvelope() should fail on trailing bytes")
+	}
+	if _, _, _, err := DecodeEnvelope([]byte{0xFF, 0xFF}); err == nil {
+		t.Error("DecodeEnvelope() should fail on unknown code")
+	}
+	if _, err := (&Envelope{Code: 99}).Encode(); err == nil {
+		t.Error("Encode() should fail on unknown code")
+	}
+}
+
+func TestReadEnvelopeDataWithLimits(t *testing.T) {
+	s, _ := signer.New()
+	e, _, err := Seal(&UserData{Data: make([]byte, 100)}, s, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var lerr *serializer.LimitError
+	for _, limits := range []serializer.Limits{{MaxFieldBytes: 99}, {MaxTransactionBytes: 100}} {
+		if _, _, _, err := ReadEnvelopeDataWithLimits(e.Code, bytes.NewReader(e.Data), limits); !errors.As(err, &lerr) {
+			t.Fatalf("ReadEnvelopeDataWithLimits(%+v) error = %v, want limit error", limits, err)
+		}
+	}
+	if _, _, _, err := ReadEnvelopeDataWithLimits(e.Code, bytes.NewReader(e.Data), serializer.Limits{}); err != nil {
+		t.Fatal(err)
+	}
+}
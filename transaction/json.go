@@ -0,0 +1,344 @@
+package transaction
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"unicode"
+
+	mint "github.com/void616/gm.mint"
+	"github.com/void616/gm.mint/serializer"
+)
+
+// JSON is a canonical JSON form of a transaction, like:
+// {"type":"transfer_asset","nonce":"1","from":"...","body":{"token":"GOLD","address":"...","amount":"1.5"},"signature":"...","digest":"..."}.
+// Signature is omitted for an unsigned transaction.
+// Body fields go in the payload order and are named in snake case (AmountMNT is "amount_mnt"),
+// tokens and wallet tags are names (codes are accepted too), other fields have their default JSON encoding
+type JSON struct {
+	// Nonce of the transaction
+	Nonce uint64
+	// From is the sender public key
+	From mint.PublicKey
+	// Body is the transaction
+	Body Transactioner
+	// Signature, nil for unsigned transaction
+	Signature *mint.Signature
+	// Digest of the payload
+	Digest mint.Digest
+}
+
+// jsonForm is JSON layout
+type jsonForm struct {
+	Type      string          `json:"type"`
+	Nonce     uint64          `json:"nonce,string"`
+	From      mint.PublicKey  `json:"from"`
+	Body      json.RawMessage `json:"body"`
+	Signature *mint.Signature `json:"signature,omitempty"`
+	Digest    *mint.Digest    `json:"digest,omitempty"`
+}
+
+// ToJSON makes a canonical JSON form of the transaction data (signed or unsigned).
+// Data must be canonical: the data encoded back from the JSON form must be the same
+func ToJSON(code Code, data []byte) (*JSON, error) {
+	tx, err := CodeToTransaction(code)
+	if err != nil {
+		return nil, err
+	}
+	des := serializer.NewDeserializer(data)
+	ptx, err := ParseDeserializer(tx, des)
+	if err != nil {
+		return nil, err
+	}
+	if int(des.Offset()) != len(data) {
+		return nil, fmt.Errorf("transaction data has %v trailing bytes", len(data)-int(des.Offset()))
+	}
+
+	ret := &JSON{
+		Nonce:  ptx.Nonce,
+		From:   ptx.From,
+		Body:   tx,
+		Digest: ptx.Digest,
+	}
+	if ptx.Signed {
+		sig := ptx.Signature
+		ret.Signature = &sig
+	}
+
+	// ensure lossless conversion
+	back, err := ret.Data()
+	if err != nil {
+		return nil, err
+	}
+	if !bytes.Equal(back, data) {
+		return nil, fmt.Errorf("transaction data is not canonical")
+	}
+	return ret, nil
+}
+
+// NewJSON makes a JSON form of the transaction sent from `from`, signature is nil for unsigned transaction
+func NewJSON(tx Transactioner, from mint.PublicKey, nonce uint64, signature *mint.Signature) (*JSON, error) {
+	u, err := construct(tx, from, nonce).Unsigned()
+	if err != nil {
+		return nil, err
+	}
+	return &JSON{
+		Nonce:     nonce,
+		From:      from,
+		Body:      tx,
+		Signature: signature,
+		Digest:    u.Digest,
+	}, nil
+}
+
+// FromSigned makes a canonical JSON form of the signed transaction
+func FromSigned(code Code, tx *SignedTransaction) (*JSON, error) {
+	return ToJSON(code, tx.Data)
+}
+
+// Data is a binary form of the transaction: the payload followed by the signature, or by the digest if the transaction is unsigned
+func (j *JSON) Data() ([]byte, error) {
+	if j.Body == nil {
+		return nil, fmt.Errorf("transaction body is nil")
+	}
+	ctor := construct(j.Body, j.From, j.Nonce)
+	payload, err := ctor.Data()
+	if err != nil {
+		return nil, err
+	}
+	digest, err := payloadDigest(payload)
+	if err != nil {
+		return nil, err
+	}
+	if digest != j.Digest {
+		return nil, fmt.Errorf("digest mismatch, expected %v", digest)
+	}
+	if j.Signature != nil {
+		ctor.PutByte(1)                 // "signed bit"
+		ctor.PutSignature(*j.Signature) // signature
+	} else {
+		ctor.PutByte(0)        // "signed bit"
+		ctor.PutDigest(digest) // digest
+	}
+	return ctor.Data()
+}
+
+// SignedTransaction is a binary form of the signed transaction
+func (j *JSON) SignedTransaction() (*SignedTransaction, error) {
+	if j.Signature == nil {
+		return nil, fmt.Errorf("transaction is unsigned")
+	}
+	data, err := j.Data()
+	if err != nil {
+		return nil, err
+	}
+	return &SignedTransaction{
+		Digest:    j.Digest,
+		Data:      data,
+		Signature: *j.Signature,
+	}, nil
+}
+
+// MarshalJSON impl
+func (j *JSON) MarshalJSON() ([]byte, error) {
+	if j.Body == nil {
+		return nil, fmt.Errorf("transaction body is nil")
+	}
+	body, err := marshalBody(j.Body)
+	if err != nil {
+		return nil, err
+	}
+	digest := j.Digest
+	return json.Marshal(&jsonForm{
+		Type:      j.Body.Code().String(),
+		Nonce:     j.Nonce,
+		From:      j.From,
+		Body:      body,
+		Signature: j.Signature,
+		Digest:    &digest,
+	})
+}
+
+// UnmarshalJSON impl, the transaction type is resolved with ParseCode.
+// The digest is calculated from the transaction fields, it's optional in JSON, but must match if specified
+func (j *JSON) UnmarshalJSON(b []byte) error {
+	var form jsonForm
+	if err := strictUnmarshal(b, &form); err != nil {
+		return err
+	}
+	code, err := ParseCode(form.Type)
+	if err != nil {
+		return err
+	}
+	tx, err := CodeToTransaction(code)
+	if err != nil {
+		return err
+	}
+	if len(form.Body) == 0 {
+		return fmt.Errorf("transaction body is missing")
+	}
+	if err := unmarshalBody(form.Body, tx); err != nil {
+		return fmt.Errorf("%v body: %v", form.Type, err)
+	}
+
+	u, err := construct(tx, form.From, form.Nonce).Unsigned()
+	if err != nil {
+		return err
+	}
+	if form.Digest != nil && *form.Digest != u.Digest {
+		return fmt.Errorf("digest mismatch, expected %v", u.Digest)
+	}
+
+	*j = JSON{
+		Nonce:     form.Nonce,
+		From:      form.From,
+		Body:      tx,
+		Signature: form.Signature,
+		Digest:    u.Digest,
+	}
+	return nil
+}
+
+// strictUnmarshal unmarshals a single JSON value disallowing unknown fields
+func strictUnmarshal(b []byte, v interface{}) error {
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(v); err != nil {
+		return err
+	}
+	if dec.More() {
+		return fmt.Errorf("unexpected data after JSON value")
+	}
+	return nil
+}
+
+// marshalBody encodes the transaction fields (see JSON)
+func marshalBody(tx Transactioner) ([]byte, error) {
+	l, err := layoutOf(tx)
+	if err != nil {
+		return nil, err
+	}
+	v := reflect.ValueOf(tx).Elem()
+	buf := bytes.NewBufferString("{")
+	for _, f := range l.fields {
+		if f.kind == kindFrom {
+			continue
+		}
+		var val interface{}
+		fv := v.Field(f.index)
+		switch f.kind {
+		case kindToken:
+			if val = mint.Token(fv.Uint()).String(); val == "" {
+				return nil, fmt.Errorf("%v.%v: unknown token with code `%v`", l.name, f.name, fv.Uint())
+			}
+		case kindWalletTag:
+			if val = mint.WalletTag(fv.Uint()).String(); val == "" {
+				return nil, fmt.Errorf("%v.%v: unknown wallet tag with code `%v`", l.name, f.name, fv.Uint())
+			}
+		default:
+			val = fv.Interface()
+		}
+		b, err := json.Marshal(val)
+		if err != nil {
+			return nil, fmt.Errorf("%v.%v: %v", l.name, f.name, err)
+		}
+		if buf.Len() > 1 {
+			buf.WriteByte(',')
+		}
+		buf.WriteString(`"` + jsonName(f.name) + `":`)
+		buf.Write(b)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+// unmarshalBody decodes the transaction fields (see JSON), unknown fields are not allowed, missing ones are left as is
+func unmarshalBody(b []byte, tx Transactioner) error {
+	l, err := layoutOf(tx)
+	if err != nil {
+		return err
+	}
+	var doc map[string]json.RawMessage
+	if err := strictUnmarshal(b, &doc); err != nil {
+		return err
+	}
+	v := reflect.ValueOf(tx).Elem()
+	for _, f := range l.fields {
+		if f.kind == kindFrom {
+			continue
+		}
+		name := jsonName(f.name)
+		raw, ok := doc[name]
+		if !ok {
+			continue
+		}
+		delete(doc, name)
+		fv := v.Field(f.index)
+		switch f.kind {
+		case kindToken:
+			code, err := unmarshalCode(raw, func(s string) (uint64, error) {
+				t, err := mint.ParseToken(s)
+				return uint64(t), err
+			})
+			if err == nil && !mint.ValidToken(uint16(code)) {
+				err = fmt.Errorf("unknown token with code `%v`", code)
+			}
+			if err != nil {
+				return fmt.Errorf("%v: %v", name, err)
+			}
+			fv.SetUint(code)
+		case kindWalletTag:
+			code, err := unmarshalCode(raw, func(s string) (uint64, error) {
+				t, err := mint.ParseWalletTag(s)
+				return uint64(t), err
+			})
+			if err == nil && (code > 0xFF || !mint.ValidWalletTag(uint8(code))) {
+				err = fmt.Errorf("unknown wallet tag with code `%v`", code)
+			}
+			if err != nil {
+				return fmt.Errorf("%v: %v", name, err)
+			}
+			fv.SetUint(code)
+		default:
+			if err := json.Unmarshal(raw, fv.Addr().Interface()); err != nil {
+				return fmt.Errorf("%v: %v", name, err)
+			}
+		}
+	}
+	for name := range doc {
+		return fmt.Errorf("unknown field %q", name)
+	}
+	return nil
+}
+
+// unmarshalCode gets a code from a JSON number or a name
+func unmarshalCode(b []byte, parse func(string) (uint64, error)) (uint64, error) {
+	var code uint16
+	if err := json.Unmarshal(b, &code); err == nil {
+		return uint64(code), nil
+	}
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		return 0, err
+	}
+	return parse(s)
+}
+
+// jsonName converts a field name to snake case: NodeIP is "node_ip"
+func jsonName(name string) string {
+	var b strings.Builder
+	for i, r := range name {
+		if unicode.IsUpper(r) {
+			prevLower := i > 0 && unicode.IsLower(rune(name[i-1]))
+			nextLower := i > 0 && i+1 < len(name) && unicode.IsUpper(rune(name[i-1])) && unicode.IsLower(rune(name[i+1]))
+			if prevLower || nextLower {
+				b.WriteByte('_')
+			}
+			r = unicode.ToLower(r)
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
@@ -0,0 +1,136 @@
+package transaction
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	mint "github.com/void616/gm.mint"
+	"github.com/void616/gm.mint/amount"
+	"github.com/void616/gm.mint/signer"
+)
+
+func TestJSON_RoundTrip(t *testing.T) {
+	s, _ := signer.New()
+	txs := []Transactioner{
+		&RegisterNode{NodeAddress: s.PublicKey(), NodeIP: "127.0.0.1"},
+		&UnregisterNode{NodeAddress: s.PublicKey()},
+		&TransferAsset{Address: s.PublicKey(), Token: mint.TokenGOLD, Amount: amount.MustFromString("-1.666")},
+		&UserData{Data: []byte{}},
+		&SetWalletTag{Address: s.PublicKey(), Tag: mint.WalletTagSupervisor},
+		&UnsetWalletTag{Address: s.PublicKey(), Tag: mint.WalletTagEmission},
+		&DistributionFee{OwnerAddress: s.PublicKey(), AmountMNT: amount.MustFromString("1.666"), AmountGOLD: amount.MustFromString("666.1")},
+	}
+	for _, tx := range txs {
+		t.Run(tx.Code().String(), func(t *testing.T) {
+			// unsigned constructor to keep invalid values (negative amount, empty data)
+			signed, err := construct(tx, s.PublicKey(), 1<<60).Sign(s)
+			if err != nil {
+				t.Fatal(err)
+			}
+			unsigned, err := Unsigned(tx, s.PublicKey(), 7)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			for _, data := range [][]byte{signed.Data, unsigned.Data} {
+				j, err := ToJSON(tx.Code(), data)
+				if err != nil {
+					t.Fatal(err)
+				}
+				b, err := json.Marshal(j)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if !strings.HasPrefix(string(b), `{"type":"`+tx.Code().String()+`","nonce":"`) {
+					t.Fatalf("MarshalJSON() = %s", b)
+				}
+
+				j2 := &JSON{}
+				if err := json.Unmarshal(b, j2); err != nil {
+					t.Fatalf("UnmarshalJSON(%s) error = %v", b, err)
+				}
+				back, err := j2.Data()
+				if err != nil {
+					t.Fatal(err)
+				}
+				if !bytes.Equal(back, data) {
+					t.Fatalf("Data() = %x, want %x", back, data)
+				}
+			}
+
+			j, _ := FromSigned(tx.Code(), signed)
+			st, err := j.SignedTransaction()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if st.Digest != signed.Digest || st.Signature != signed.Signature || !bytes.Equal(st.Data, signed.Data) {
+				t.Fatal("SignedTransaction() differs")
+			}
+		})
+	}
+}
+
+func TestJSON_Canonical(t *testing.T) {
+	var from mint.PublicKey
+	from[0] = 1
+	j, err := NewJSON(&TransferAsset{Token: mint.TokenGOLD, Amount: amount.MustFromString("1.5")}, from, 3, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := json.Marshal(j)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `{"type":"transfer_asset","nonce":"3","from":"` + from.String() + `","body":{"token":"GOLD","address":"` + mint.PublicKey{}.String() + `","amount":"1.500000000000000000"},"digest":"` + j.Digest.String() + `"}`
+	if string(b) != want {
+		t.Fatalf("MarshalJSON() = %s, want %s", b, want)
+	}
+
+	// token code instead of the name
+	j2 := &JSON{}
+	if err := json.Unmarshal([]byte(strings.Replace(want, `"GOLD"`, `1`, 1)), j2); err != nil || j2.Body.(*TransferAsset).Token != mint.TokenGOLD {
+		t.Fatalf("UnmarshalJSON() = %+v, %v", j2.Body, err)
+	}
+	if err := json.Unmarshal([]byte(strings.Replace(want, `"GOLD"`, `9`, 1)), j2); err == nil {
+		t.Fatal("UnmarshalJSON() should fail on unknown token")
+	}
+}
+
+func TestJSON_Errors(t *testing.T) {
+	s, _ := signer.New()
+	signed, err := (&UserData{Data: []byte{1}}).Sign(s, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	j, _ := ToJSON(UserDataTx, signed.Data)
+	good, _ := json.Marshal(j)
+
+	tests := []struct {
+		name string
+		doc  string
+	}{
+		{"unknown type", strings.Replace(string(good), `"user_data"`, `"user_info"`, 1)},
+		{"unknown field", strings.Replace(string(good), `"body":{`, `"body":{"extra":1,`, 1)},
+		{"digest mismatch", strings.Replace(string(good), `"nonce":"1"`, `"nonce":"2"`, 1)},
+		{"no body", `{"type":"user_data","nonce":"1","from":"` + s.PublicKey().String() + `"}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := json.Unmarshal([]byte(tt.doc), &JSON{}); err == nil {
+				t.Errorf("UnmarshalJSON(%v) should fail", tt.doc)
+			}
+		})
+	}
+
+	// not canonical binary forms
+	data := append([]byte{}, signed.Data...)
+	data[len(data)-mint.SignatureSize-1] = 2 // "signed bit"
+	if _, err := ToJSON(UserDataTx, data); err == nil {
+		t.Error("ToJSON() should fail on non-canonical signed bit")
+	}
+	if _, err := ToJSON(UserDataTx, append(signed.Data, 0)); err == nil {
+		t.Error("ToJSON() should fail on trailing bytes")
+	}
+}
@@ -12,7 +12,9 @@ import (
 // parser parses transaction
 type parser struct {
 	*serializer.Deserializer
+	// payload is written here to get its digest, nil if the digest is made by encoding the transaction back
 	digestWriter *bytes.Buffer
+	tx           interface{}
 
 	nonce uint64
 }
@@ -23,24 +25,63 @@ type ParsedTransaction struct {
 	From      mint.PublicKey
 	Digest    mint.Digest
 	Signature mint.Signature
+	// Signed is false if the transaction data ends with the digest instead of a signature
+	Signed bool
 }
 
-func newParser(r io.Reader) (*parser, error) {
+// ParseDeserializer parses transaction data with the deserializer (see serializer package) starting at its current offset.
+// Field labels are prefixed with the transaction type name, like "TransferAsset.Amount" (nested into the current prefix of the deserializer, if any).
+// The deserializer limits are not changed
+func ParseDeserializer(tx Transactioner, d *serializer.Deserializer) (*ParsedTransaction, error) {
+	l, err := layoutOf(tx)
+	if err != nil {
+		return nil, err
+	}
+	prefix := d.LabelPrefix()
+	name := l.name
+	if prefix != "" {
+		name = prefix + "." + name
+	}
+	d.Prefix(name)
+	defer d.Prefix(prefix)
+	// the digest is computed from re-serialized data, so only canonical encodings are accepted
+	strict := d.IsStrict()
+	d.Strict(true)
+	defer d.Strict(strict)
+
+	p := &parser{
+		Deserializer: d,
+		tx:           tx,
+	}
+	p.nonce = p.Field("Nonce").GetUint64()
+	from := decode(d, tx)
+	return p.Complete(from)
+}
+
+// ParseWithLimits parses transaction data of the type with specified decoding limits (MaxFieldBytes and MaxTransactionBytes),
+// Transactioner.Parse applies serializer.DefaultLimits
+func ParseWithLimits(tx Transactioner, r io.Reader, limits serializer.Limits) (*ParsedTransaction, error) {
+	return parse(tx, r, limits)
+}
+
+func newParser(r io.Reader, name string, limits serializer.Limits) (*parser, error) {
 	digestWriter := bytes.NewBuffer(make([]byte, 256))
 	digestWriter.Reset()
 	des := serializer.NewStreamDeserializer(io.TeeReader(r, digestWriter))
+	des.Limit(limits.MaxFieldBytes, limits.MaxTransactionBytes)
+	des.Prefix(name)
+
+	p := &parser{
+		Deserializer: des,
+		digestWriter: digestWriter,
+	}
 
 	// read nonce
-	txnonce := des.GetUint64()
+	p.nonce = p.Field("Nonce").GetUint64()
 	if err := des.Error(); err != nil {
 		return nil, err
 	}
-
-	return &parser{
-		Deserializer: des,
-		digestWriter: digestWriter,
-		nonce:        txnonce,
-	}, nil
+	return p, nil
 }
 
 // Complete completes parsing and returns a parsed transaction common data
@@ -52,7 +93,7 @@ func (p *parser) Complete(from mint.PublicKey) (*ParsedTransaction, error) {
 
 	// calc tx digest
 	var digest mint.Digest
-	{
+	if p.digestWriter != nil {
 		hasher := sha3.New256()
 		_, err := hasher.Write(p.digestWriter.Bytes())
 		if err != nil {
@@ -60,10 +101,17 @@ func (p *parser) Complete(from mint.PublicKey) (*ParsedTransaction, error) {
 		}
 		b := hasher.Sum(nil)
 		copy(digest[:], b)
+	} else {
+		// the codec is symmetric, so the payload is the same
+		u, err := construct(p.tx, from, p.nonce).Unsigned()
+		if err != nil {
+			return nil, err
+		}
+		digest = u.Digest
 	}
 
 	// "signed" byte
-	var signed = p.GetByte()
+	var signed = p.Field("Signed").GetByte()
 	if err := p.Error(); err != nil {
 		return nil, err
 	}
@@ -73,15 +121,14 @@ func (p *parser) Complete(from mint.PublicKey) (*ParsedTransaction, error) {
 	{
 		if signed != 0 {
 			// signature
-			b := p.GetBytes(mint.SignatureSize)
+			signature = p.Field("Signature").GetSignature()
 			if err := p.Error(); err != nil {
 				return nil, err
 			}
-			copy(signature[:], b)
 			// TODO: verify signature?
 		} else {
 			// digest
-			_ = p.GetBytes(mint.DigestSize)
+			_ = p.Field("Digest").GetDigest()
 			if err := p.Error(); err != nil {
 				return nil, err
 			}
@@ -94,5 +141,6 @@ func (p *parser) Complete(from mint.PublicKey) (*ParsedTransaction, error) {
 		Nonce:     p.nonce,
 		Digest:    digest,
 		Signature: signature,
+		Signed:    signed != 0,
 	}, nil
 }
@@ -0,0 +1,171 @@
+package transaction
+
+import (
+	"sort"
+	"strconv"
+
+	mint "github.com/void616/gm.mint"
+)
+
+// SchemaVersion is a version of the wire schema, it's incremented on any change of the transactions encoding
+const SchemaVersion = 1
+
+// Schema is a machine-readable description of the transactions wire format (see schema.json)
+type Schema struct {
+	// Version of the schema
+	Version int `json:"version"`
+	// ByteOrder of integers
+	ByteOrder string `json:"byte_order"`
+	// Types are primitive types of the fields
+	Types []SchemaType `json:"types"`
+	// Payload is the data to sign: nonce followed by transaction fields
+	Payload string `json:"payload"`
+	// Digest of the payload
+	Digest string `json:"digest"`
+	// Signature of the digest
+	Signature string `json:"signature"`
+	// Signed transaction data: payload followed by these fields
+	Signed []SchemaField `json:"signed"`
+	// Unsigned transaction data: payload followed by these fields
+	Unsigned []SchemaField `json:"unsigned"`
+	// BlockCode is a type of the transaction code prefixing transaction data inside a block
+	BlockCode string `json:"block_code"`
+	// Transactions by code
+	Transactions []SchemaTransaction `json:"transactions"`
+}
+
+// SchemaType is a primitive type of the wire format
+type SchemaType struct {
+	// Name of the type, like "u16" or "amount"
+	Name string `json:"name"`
+	// Size in bytes, zero for variable size
+	Size int `json:"size"`
+	// Encoding description
+	Encoding string `json:"encoding"`
+	// Values allowed (code => name), optional
+	Values map[string]string `json:"values,omitempty"`
+}
+
+// SchemaTransaction describes a transaction payload
+type SchemaTransaction struct {
+	// Code of the transaction
+	Code uint16 `json:"code"`
+	// Name of the code, like "transfer_asset"
+	Name string `json:"name"`
+	// Type name in Go, like "TransferAsset"
+	Type string `json:"type"`
+	// Fields of the payload in order, starting with the nonce
+	Fields []SchemaField `json:"fields"`
+}
+
+// SchemaField is a field of the wire format
+type SchemaField struct {
+	// Name of the field, like "Amount"
+	Name string `json:"name"`
+	// Type of the field (see SchemaType)
+	Type string `json:"type"`
+	// Size in bytes, zero for variable size
+	Size int `json:"size"`
+	// Value is a constant value of the field, optional
+	Value *int `json:"value,omitempty"`
+	// Description of the field, optional
+	Description string `json:"description,omitempty"`
+}
+
+// schemaTypes are the primitives in order of the description
+func schemaTypes() []SchemaType {
+	return []SchemaType{
+		{Name: kindUint8, Size: 1, Encoding: "unsigned integer"},
+		{Name: kindUint16, Size: 2, Encoding: "unsigned integer"},
+		{Name: kindUint32, Size: 4, Encoding: "unsigned integer"},
+		{Name: kindUint64, Size: 8, Encoding: "unsigned integer"},
+		{Name: kindPublicKey, Size: mint.PublicKeySize, Encoding: "Ed25519 public key bytes"},
+		{Name: kindDigest, Size: mint.DigestSize, Encoding: "SHA3-256 digest bytes"},
+		{Name: kindSignature, Size: mint.SignatureSize, Encoding: "Ed25519 signature bytes"},
+		{Name: kindAmount, Size: 16, Encoding: "sign byte (0 - positive, 1 - negative, negative zero is non-canonical); " +
+			"fraction part, 18 decimal digits as 9 BCD bytes, least significant byte first; " +
+			"integer part, 12 decimal digits as 6 BCD bytes, least significant byte first. " +
+			"BCD byte holds two digits: the higher one in the high nibble, i.e. 1.5 is 00 00 00 00 00 00 00 00 00 50 01 00 00 00 00 00"},
+		{Name: kindString64, Size: 64, Encoding: "UTF-8 string up to 64 bytes without zero bytes, padded with zeros"},
+		{Name: kindBytes, Encoding: "u32 length followed by the bytes"},
+		{Name: kindToken, Size: 2, Encoding: "u16 token code", Values: tokenValues()},
+		{Name: kindWalletTag, Size: 1, Encoding: "u8 wallet tag code", Values: walletTagValues()},
+	}
+}
+
+// WireSchema describes the transactions wire format
+func WireSchema() *Schema {
+	one, zero := 1, 0
+	s := &Schema{
+		Version:   SchemaVersion,
+		ByteOrder: "little-endian",
+		Types:     schemaTypes(),
+		Payload:   "nonce (u64) followed by the transaction fields",
+		Digest:    "SHA3-256 of the payload",
+		Signature: "Ed25519 signature of the payload digest (32 bytes) made with the sender private key, the sender public key is the field `From`",
+		Signed: []SchemaField{
+			{Name: "Signed", Type: kindUint8, Size: 1, Value: &one},
+			{Name: "Signature", Type: kindSignature, Size: mint.SignatureSize},
+		},
+		Unsigned: []SchemaField{
+			{Name: "Signed", Type: kindUint8, Size: 1, Value: &zero},
+			{Name: "Digest", Type: kindDigest, Size: mint.DigestSize, Description: "payload digest"},
+		},
+		BlockCode: kindUint16,
+	}
+
+	sizes := make(map[string]int)
+	for _, t := range s.Types {
+		sizes[t.Name] = t.Size
+	}
+
+	codes := make([]Code, 0, len(codeToString))
+	for c := range codeToString {
+		codes = append(codes, c)
+	}
+	sort.Slice(codes, func(i, j int) bool { return codes[i] < codes[j] })
+
+	for _, c := range codes {
+		tx, err := CodeToTransaction(c)
+		if err != nil {
+			continue
+		}
+		l, err := layoutOf(tx)
+		if err != nil {
+			panic(err)
+		}
+		st := SchemaTransaction{
+			Code: uint16(c),
+			Name: c.String(),
+			Type: l.name,
+			Fields: []SchemaField{
+				{Name: "Nonce", Type: kindUint64, Size: 8},
+			},
+		}
+		for _, f := range l.fields {
+			sf := SchemaField{Name: f.name, Type: f.kind, Size: sizes[f.kind]}
+			if f.kind == kindFrom {
+				sf.Type, sf.Size, sf.Description = kindPublicKey, mint.PublicKeySize, "sender public key"
+			}
+			st.Fields = append(st.Fields, sf)
+		}
+		s.Transactions = append(s.Transactions, st)
+	}
+	return s
+}
+
+func tokenValues() map[string]string {
+	ret := make(map[string]string)
+	for k, v := range mint.TokenToString {
+		ret[strconv.Itoa(int(k))] = v
+	}
+	return ret
+}
+
+func walletTagValues() map[string]string {
+	ret := make(map[string]string)
+	for k, v := range mint.WalletTagToString {
+		ret[strconv.Itoa(int(k))] = v
+	}
+	return ret
+}
@@ -0,0 +1,265 @@
+package transaction
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"math/rand"
+	"reflect"
+	"strconv"
+	"testing"
+
+	mint "github.com/void616/gm.mint"
+	"github.com/void616/gm.mint/amount"
+	"github.com/void616/gm.mint/signer"
+	"golang.org/x/crypto/sha3"
+)
+
+var updateSchema = flag.Bool("update-schema", false, "rewrite schema.json")
+
+// TestWireSchema_File checks the checked-in schema is up to date
+func TestWireSchema_File(t *testing.T) {
+	b, err := json.MarshalIndent(WireSchema(), "", "  ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	b = append(b, '\n')
+
+	if *updateSchema {
+		if err := ioutil.WriteFile("schema.json", b, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	file, err := ioutil.ReadFile("schema.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(file, b) {
+		t.Fatal("schema.json is outdated, run: go test ./transaction -run TestWireSchema_File -update-schema")
+	}
+}
+
+// TestWireSchema_Conformance encodes random transactions following the schema file and compares them with Go encoders
+func TestWireSchema_Conformance(t *testing.T) {
+	file, err := ioutil.ReadFile("schema.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	schema := &Schema{}
+	if err := json.Unmarshal(file, schema); err != nil {
+		t.Fatal(err)
+	}
+	if schema.Version != SchemaVersion || schema.ByteOrder != "little-endian" {
+		t.Fatalf("schema version %v, byte order %v", schema.Version, schema.ByteOrder)
+	}
+	types := make(map[string]SchemaType)
+	for _, st := range schema.Types {
+		types[st.Name] = st
+	}
+	if len(schema.Transactions) != len(codeToString) {
+		t.Fatalf("schema has %v transactions, want %v", len(schema.Transactions), len(codeToString))
+	}
+
+	rnd := rand.New(rand.NewSource(1))
+	sig, _ := signer.New()
+
+	for _, stx := range schema.Transactions {
+		t.Run(stx.Name, func(t *testing.T) {
+			code := Code(stx.Code)
+			if code.String() != stx.Name {
+				t.Fatalf("code %v name is %v", stx.Code, code.String())
+			}
+			for i := 0; i < 50; i++ {
+				tx, err := CodeToTransaction(code)
+				if err != nil {
+					t.Fatal(err)
+				}
+				v := reflect.ValueOf(tx).Elem()
+				if v.Type().Name() != stx.Type {
+					t.Fatalf("type is %v, want %v", v.Type().Name(), stx.Type)
+				}
+
+				// payload by the schema, transaction fields are set along
+				payload := &bytes.Buffer{}
+				var nonce uint64
+				for j, f := range stx.Fields {
+					typ, ok := types[f.Type]
+					if !ok || typ.Size != f.Size {
+						t.Fatalf("field %v has unknown type %v or wrong size %v", f.Name, f.Type, f.Size)
+					}
+					val := schemaEncode(t, rnd, payload, typ)
+					switch {
+					case j == 0:
+						if f.Name != "Nonce" {
+							t.Fatalf("first field is %v", f.Name)
+						}
+						nonce = val.(uint64)
+					case f.Name == "From":
+						val = sig.PublicKey()
+						payload.Truncate(payload.Len() - mint.PublicKeySize)
+						payload.Write(sig.PublicKey().Bytes())
+					default:
+						fv := v.FieldByName(f.Name)
+						if !fv.IsValid() {
+							t.Fatalf("field %v is not found", f.Name)
+						}
+						fv.Set(reflect.ValueOf(val).Convert(fv.Type()))
+					}
+				}
+
+				// digest and signature
+				digest := sha3.Sum256(payload.Bytes())
+				signed, err := construct(tx, sig.PublicKey(), nonce).Sign(sig) // random values are not necessarily valid
+				if err != nil {
+					t.Fatal(err)
+				}
+				if !bytes.Equal(signed.Data[:payload.Len()], payload.Bytes()) {
+					t.Fatalf("payload is %x, want %x (%#v)", signed.Data[:payload.Len()], payload.Bytes(), tx)
+				}
+				if signed.Digest != mint.Digest(digest) {
+					t.Fatal("digest mismatch")
+				}
+				if want := signedBytes(schema.Signed, signed.Signature[:], digest[:]); !bytes.Equal(signed.Data[payload.Len():], want) {
+					t.Fatalf("signed data is %x, want %x", signed.Data[payload.Len():], want)
+				}
+				unsigned, err := Unsigned(tx, sig.PublicKey(), nonce)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if want := append(payload.Bytes(), signedBytes(schema.Unsigned, signed.Signature[:], digest[:])...); !bytes.Equal(unsigned.Data, want) {
+					t.Fatalf("unsigned data is %x, want %x", unsigned.Data, want)
+				}
+			}
+		})
+	}
+}
+
+// schemaEncode writes a random value of the type according to the schema and returns the value
+func schemaEncode(t *testing.T, rnd *rand.Rand, w *bytes.Buffer, typ SchemaType) interface{} {
+	le := binary.LittleEndian
+	fixed := func() []byte {
+		b := make([]byte, typ.Size)
+		rnd.Read(b)
+		w.Write(b)
+		return b
+	}
+	enum := func() int {
+		keys := make([]int, 0, len(typ.Values))
+		for k := range typ.Values {
+			v, err := strconv.Atoi(k)
+			if err != nil {
+				t.Fatal(err)
+			}
+			keys = append(keys, v)
+		}
+		if len(keys) == 0 {
+			t.Fatalf("type %v has no values", typ.Name)
+		}
+		return keys[rnd.Intn(len(keys))]
+	}
+
+	switch typ.Name {
+	case "u8":
+		return fixed()[0]
+	case "u16":
+		return le.Uint16(fixed())
+	case "u32":
+		return le.Uint32(fixed())
+	case "u64":
+		return le.Uint64(fixed())
+	case "pubkey":
+		return mint.MustBytesToPublicKey(fixed())
+	case "digest":
+		return mint.MustBytesToDigest(fixed())
+	case "signature":
+		return mint.MustBytesToSignature(fixed())
+	case "token":
+		v := uint16(enum())
+		binary.Write(w, le, v)
+		return mint.Token(v)
+	case "wallettag":
+		v := uint8(enum())
+		w.WriteByte(v)
+		return mint.WalletTag(v)
+	case "string64":
+		b := make([]byte, rnd.Intn(65))
+		for i := range b {
+			b[i] = byte(0x20 + rnd.Intn(0x5F))
+		}
+		w.Write(b)
+		w.Write(make([]byte, 64-len(b)))
+		return string(b)
+	case "u32len":
+		b := make([]byte, rnd.Intn(100))
+		rnd.Read(b)
+		binary.Write(w, le, uint32(len(b)))
+		w.Write(b)
+		return b
+	case "amount":
+		// up to 12 integer digits and 18 fraction digits
+		integer := rnd.Int63n(1000000000000)
+		fraction := rnd.Int63n(1000000000000000000)
+		neg := rnd.Intn(2) == 1 && integer+fraction > 0
+		if neg {
+			w.WriteByte(1)
+		} else {
+			w.WriteByte(0)
+		}
+		w.Write(bcd(fmt.Sprintf("%018d", fraction)))
+		w.Write(bcd(fmt.Sprintf("%012d", integer)))
+
+		a := amount.New()
+		a.Value.SetInt64(integer)
+		a.Value.Mul(a.Value, new(big.Int).Exp(big.NewInt(10), big.NewInt(amount.Precision), nil))
+		a.Value.Add(a.Value, big.NewInt(fraction))
+		if neg {
+			a.Value.Neg(a.Value)
+		}
+		return a
+	}
+	t.Fatalf("unknown type %v", typ.Name)
+	return nil
+}
+
+// bcd packs decimal digits, two per byte, least significant byte first
+func bcd(digits string) []byte {
+	b := make([]byte, len(digits)/2)
+	for i := range b {
+		hi, lo := digits[len(digits)-2-2*i]-'0', digits[len(digits)-1-2*i]-'0'
+		b[i] = hi<<4 | lo
+	}
+	return b
+}
+
+// signedBytes makes the fields following the payload
+func signedBytes(fields []SchemaField, signature, digest []byte) []byte {
+	var ret []byte
+	for _, f := range fields {
+		switch {
+		case f.Value != nil:
+			ret = append(ret, byte(*f.Value))
+		case f.Type == "signature":
+			ret = append(ret, signature...)
+		case f.Type == "digest":
+			ret = append(ret, digest...)
+		}
+	}
+	return ret
+}
+
+func TestWireSchema_AmountExample(t *testing.T) {
+	u, err := Unsigned(&TransferAsset{Amount: amount.MustFromString("1.5")}, mint.PublicKey{}, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := fmt.Sprintf("% x", u.Payload[len(u.Payload)-16:])
+	for _, typ := range WireSchema().Types {
+		if typ.Name == "amount" && !bytes.HasSuffix([]byte(typ.Encoding), []byte(got)) {
+			t.Fatalf("amount example is wrong, 1.5 is %v", got)
+		}
+	}
+}
@@ -12,6 +12,8 @@ type Transactioner interface {
 	Sign(signer *signer.Signer, nonce uint64) (*SignedTransaction, error)
 	Parse(r io.Reader) (*ParsedTransaction, error)
 	Code() Code
+	// Validate checks field values, it's called before signing (see ValidateSender)
+	Validate() error
 }
 
 // CodeToTransaction returns corresponding transaction data holder
@@ -2,17 +2,21 @@ package transaction
 
 import (
 	"bytes"
+	"errors"
 	"math/rand"
 	"reflect"
 	"testing"
+	"testing/iotest"
 
 	mint "github.com/void616/gm.mint"
 	"github.com/void616/gm.mint/amount"
+	"github.com/void616/gm.mint/serializer"
 	"github.com/void616/gm.mint/signer"
 )
 
 func TestConstructParse(t *testing.T) {
 
+	dst, _ := signer.New()
 	signer, _ := signer.New()
 
 	tests := []struct {
@@ -35,7 +39,7 @@ func TestConstructParse(t *testing.T) {
 		{
 			"transfer_asset",
 			&TransferAsset{
-				Address: signer.PublicKey(),
+				Address: dst.PublicKey(),
 				Token:   mint.TokenGOLD,
 				Amount:  amount.MustFromString("1.666"),
 			},
@@ -96,3 +100,57 @@ func TestConstructParse(t *testing.T) {
 		})
 	}
 }
+
+func TestParseShortReads(t *testing.T) {
+
+	dst, _ := signer.New()
+	signer, _ := signer.New()
+
+	tx := &TransferAsset{
+		Address: dst.PublicKey(),
+		Token:   mint.TokenGOLD,
+		Amount:  amount.MustFromString("1.666"),
+	}
+	signed, err := tx.Sign(signer, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// short reads
+	parsed := &TransferAsset{}
+	ptx, err := parsed.Parse(iotest.OneByteReader(bytes.NewReader(signed.Data)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ptx.Digest != signed.Digest || !reflect.DeepEqual(tx, parsed) {
+		t.Fatal("Constructed and parsed are not equal")
+	}
+
+	// truncated
+	_, err = (&TransferAsset{}).Parse(bytes.NewReader(signed.Data[:80]))
+	if err == nil || err.Error() != "TransferAsset.Amount at offset 74: unexpected EOF" {
+		t.Fatalf("Parse() error = %v", err)
+	}
+}
+
+func TestParseLimits(t *testing.T) {
+
+	// 44-byte user data transaction claiming 4 GiB of data
+	ser := serializer.NewSerializer()
+	ser.PutUint64(1)                   // nonce
+	ser.PutPublicKey(mint.PublicKey{}) // signer public key
+	ser.PutUint32(0xFFFFFFFF)          // data size
+	dat, err := ser.Data()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = (&UserData{}).Parse(bytes.NewReader(dat))
+	var lerr *serializer.LimitError
+	if !errors.As(err, &lerr) || lerr.Limit != serializer.LimitField {
+		t.Fatalf("Parse() error = %v, want field limit error", err)
+	}
+	if err.Error() != "UserData.Data at offset 44: field limit exceeded, got 4294967295, max 1048576" {
+		t.Fatal(err)
+	}
+}
@@ -5,6 +5,7 @@ import (
 
 	mint "github.com/void616/gm.mint"
 	"github.com/void616/gm.mint/amount"
+	"github.com/void616/gm.mint/serializer"
 	"github.com/void616/gm.mint/signer"
 )
 
@@ -12,34 +13,19 @@ var _ = Transactioner(&DistributionFee{})
 
 // DistributionFee transaction data
 type DistributionFee struct {
-	OwnerAddress mint.PublicKey
-	AmountMNT    *amount.Amount
-	AmountGOLD   *amount.Amount
+	OwnerAddress mint.PublicKey `mint:"pubkey"`
+	AmountMNT    *amount.Amount `mint:"amount"`
+	AmountGOLD   *amount.Amount `mint:"amount"`
 }
 
 // Sign impl
 func (t *DistributionFee) Sign(signer *signer.Signer, nonce uint64) (*SignedTransaction, error) {
-	ctor := newConstructor(nonce)
-	ctor.PutPublicKey(signer.PublicKey()) // signer public key
-	ctor.PutPublicKey(t.OwnerAddress)     // owner address / public key
-	ctor.PutAmount(t.AmountMNT)           // mnt amount
-	ctor.PutAmount(t.AmountGOLD)          // gold amount
-	return ctor.Sign(signer)
+	return sign(t, signer, nonce)
 }
 
 // Parse impl.
 func (t *DistributionFee) Parse(r io.Reader) (*ParsedTransaction, error) {
-	pars, err := newParser(r)
-	if err != nil {
-		return nil, err
-	}
-
-	from := pars.GetPublicKey()          // signer public key
-	t.OwnerAddress = pars.GetPublicKey() // owner address / public key
-	t.AmountMNT = pars.GetAmount()       // mnt amount
-	t.AmountGOLD = pars.GetAmount()      // gold amount
-
-	return pars.Complete(from)
+	return parse(t, r, serializer.DefaultLimits)
 }
 
 // Code impl
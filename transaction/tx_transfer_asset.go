@@ -1,11 +1,11 @@
 package transaction
 
 import (
-	"fmt"
 	"io"
 
 	mint "github.com/void616/gm.mint"
 	"github.com/void616/gm.mint/amount"
+	"github.com/void616/gm.mint/serializer"
 	"github.com/void616/gm.mint/signer"
 )
 
@@ -13,37 +13,24 @@ var _ = Transactioner(&TransferAsset{})
 
 // TransferAsset transaction data
 type TransferAsset struct {
-	Address mint.PublicKey
-	Token   mint.Token
-	Amount  *amount.Amount
+	Address mint.PublicKey `mint:"pubkey"`
+	Token   mint.Token     `mint:"token"`
+	Amount  *amount.Amount `mint:"amount"`
+}
+
+// the token goes first on the wire
+func (t *TransferAsset) wireOrder() []string {
+	return []string{"Token", fieldFrom, "Address", "Amount"}
 }
 
 // Sign impl
 func (t *TransferAsset) Sign(signer *signer.Signer, nonce uint64) (*SignedTransaction, error) {
-	ctor := newConstructor(nonce)
-	ctor.PutUint16(uint16(t.Token))       // token
-	ctor.PutPublicKey(signer.PublicKey()) // signer public key
-	ctor.PutPublicKey(t.Address)          // address / public key
-	ctor.PutAmount(t.Amount)              // amount
-	return ctor.Sign(signer)
+	return sign(t, signer, nonce)
 }
 
 // Parse impl
 func (t *TransferAsset) Parse(r io.Reader) (*ParsedTransaction, error) {
-	pars, err := newParser(r)
-	if err != nil {
-		return nil, err
-	}
-	tokenCode := pars.GetUint16()   // token
-	from := pars.GetPublicKey()     // signer public key
-	t.Address = pars.GetPublicKey() // address / public key
-	t.Amount = pars.GetAmount()     // amount
-	// ensure token is valid
-	if !mint.ValidToken(tokenCode) {
-		return nil, fmt.Errorf("unknown token with code `%v`", tokenCode)
-	}
-	t.Token = mint.Token(tokenCode)
-	return pars.Complete(from)
+	return parse(t, r, serializer.DefaultLimits)
 }
 
 // Code impl
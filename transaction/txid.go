@@ -0,0 +1,118 @@
+package transaction
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+
+	mint "github.com/void616/gm.mint"
+)
+
+// TxID is a transaction ID: SHA3-256 digest of the transaction payload (see constructor.Sign).
+// It's the same for signed and unsigned transaction
+type TxID mint.Digest
+
+// ID of the transaction sent from `from` with the nonce, without signing it
+func ID(tx Transactioner, from mint.PublicKey, nonce uint64) (TxID, error) {
+	u, err := Unsigned(tx, from, nonce)
+	if err != nil {
+		return TxID{}, err
+	}
+	return TxID(u.Digest), nil
+}
+
+// DataID is an ID of the transaction data (signed or unsigned, see SignedTransaction.Data) of the code
+func DataID(code Code, data []byte) (TxID, error) {
+	_, ptx, err := (&Envelope{Code: code, Data: data}).Open()
+	if err != nil {
+		return TxID{}, err
+	}
+	return TxID(ptx.Digest), nil
+}
+
+// ID of the signed transaction
+func (t *SignedTransaction) ID() TxID {
+	return TxID(t.Digest)
+}
+
+// ID of the unsigned transaction
+func (t *UnsignedTransaction) ID() TxID {
+	return TxID(t.Digest)
+}
+
+// ID of the parsed transaction
+func (t *ParsedTransaction) ID() TxID {
+	return TxID(t.Digest)
+}
+
+// ID of the transaction in the envelope
+func (e *Envelope) ID() (TxID, error) {
+	return DataID(e.Code, e.Data)
+}
+
+// Digest of the ID
+func (id TxID) Digest() mint.Digest {
+	return mint.Digest(id)
+}
+
+// Bytes of the instance
+func (id TxID) Bytes() []byte {
+	return mint.Digest(id).Bytes()
+}
+
+// String packs the instance into a Base58 string (as mint.Digest)
+func (id TxID) String() string {
+	return mint.Digest(id).String()
+}
+
+// Hex representation
+func (id TxID) Hex() string {
+	return hex.EncodeToString(id[:])
+}
+
+// StringMask packs the instance into 6+4 a masked Base58 string
+func (id TxID) StringMask() string {
+	return mint.MaskString6P4(id.String())
+}
+
+// MarshalJSON impl, Base58 string
+func (id TxID) MarshalJSON() ([]byte, error) {
+	return json.Marshal(id.String())
+}
+
+// UnmarshalJSON impl, Base58 or hex string
+func (id *TxID) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		return err
+	}
+	x, err := ParseTxID(s)
+	if err != nil {
+		return err
+	}
+	*id = x
+	return nil
+}
+
+// ParseTxID parses an instance from Base58 or hex string
+func ParseTxID(s string) (TxID, error) {
+	if len(s) == hex.EncodedLen(mint.DigestSize) {
+		if b, err := hex.DecodeString(s); err == nil {
+			return TxID(mint.MustBytesToDigest(b)), nil
+		}
+	}
+	d, err := mint.ParseDigest(s)
+	if err != nil {
+		return TxID{}, fmt.Errorf("failed to parse transaction ID `%v`: %v", s, err)
+	}
+	return TxID(d), nil
+}
+
+// MustParseTxID parses an instance from Base58 or hex string or panics
+func MustParseTxID(s string) TxID {
+	v, err := ParseTxID(s)
+	if err != nil {
+		panic(err)
+	}
+	return v
+}
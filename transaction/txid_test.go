@@ -0,0 +1,51 @@
+package transaction
+
+import (
+	"encoding/json"
+	"testing"
+
+	mint "github.com/void616/gm.mint"
+	"github.com/void616/gm.mint/signer"
+)
+
+func TestTxID(t *testing.T) {
+	s, _ := signer.New()
+	tx := &SetWalletTag{Address: s.PublicKey(), Tag: mint.WalletTagApproved}
+
+	id, err := ID(tx, s.PublicKey(), 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signed, err := tx.Sign(s, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if signed.ID() != id {
+		t.Fatal("ID() differs from the signed transaction digest")
+	}
+	if got, err := DataID(SetWalletTagTx, signed.Data); err != nil || got != id {
+		t.Fatalf("DataID() = %v, %v", got, err)
+	}
+	e, _, _ := Seal(tx, s, 3)
+	if got, err := e.ID(); err != nil || got != id {
+		t.Fatalf("Envelope.ID() = %v, %v", got, err)
+	}
+
+	// forms
+	for _, str := range []string{id.String(), id.Hex()} {
+		if got, err := ParseTxID(str); err != nil || got != id {
+			t.Errorf("ParseTxID(%v) = %v, %v", str, got, err)
+		}
+	}
+	if id.String() != signed.Digest.String() || len(id.Hex()) != 64 || id.StringMask() != mint.MaskString6P4(id.String()) {
+		t.Fatal("TxID string forms differ")
+	}
+	b, _ := json.Marshal(id)
+	var id2 TxID
+	if err := json.Unmarshal(b, &id2); err != nil || id2 != id {
+		t.Fatalf("UnmarshalJSON(%s) = %v, %v", b, id2, err)
+	}
+	if _, err := ParseTxID("xyz"); err == nil {
+		t.Fatal("ParseTxID() should fail")
+	}
+}
@@ -0,0 +1,205 @@
+package transaction
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+
+	mint "github.com/void616/gm.mint"
+	"github.com/void616/gm.mint/amount"
+	"github.com/void616/gm.mint/serializer"
+	"github.com/void616/gm.mint/signer"
+)
+
+// Validation errors, see ValidationError
+var (
+	// ErrEmpty means the field is nil or empty
+	ErrEmpty = errors.New("is empty")
+	// ErrNotPositive means the amount must be greater than zero
+	ErrNotPositive = errors.New("must be positive")
+	// ErrNegative means the amount must not be negative
+	ErrNegative = errors.New("must not be negative")
+	// ErrUnknown means the code (token, tag) is not defined
+	ErrUnknown = errors.New("is unknown")
+	// ErrMalformed means the value has invalid format
+	ErrMalformed = errors.New("is malformed")
+	// ErrTooLong means the value is too long to encode
+	ErrTooLong = errors.New("is too long")
+	// ErrSender means the field must not be the sender
+	ErrSender = errors.New("must not be the sender")
+)
+
+// ValidationError is a field-level error of a transaction
+type ValidationError struct {
+	// Type of the transaction, like "TransferAsset"
+	Type string
+	// Field of the transaction, like "Amount"
+	Field string
+	// Err is one of the validation errors (ErrEmpty etc.)
+	Err error
+}
+
+// Error impl, like: TransferAsset.Amount must be positive
+func (e *ValidationError) Error() string {
+	return fmt.Sprintf("%v.%v %v", e.Type, e.Field, e.Err)
+}
+
+// Unwrap impl
+func (e *ValidationError) Unwrap() error {
+	return e.Err
+}
+
+// ValidateSender validates the transaction (see Transactioner.Validate) and the rules depending on the sender
+func ValidateSender(tx Transactioner, from mint.PublicKey) error {
+	if err := tx.Validate(); err != nil {
+		return err
+	}
+	switch t := tx.(type) {
+	case *TransferAsset:
+		if t.Address == from {
+			return invalid(t, "Address", ErrSender)
+		}
+	}
+	return nil
+}
+
+// ParseValid parses the transaction (see Transactioner.Parse) and validates it with the parsed sender (see ValidateSender)
+func ParseValid(tx Transactioner, r io.Reader) (*ParsedTransaction, error) {
+	ptx, err := tx.Parse(r)
+	if err != nil {
+		return nil, err
+	}
+	if err := ValidateSender(tx, ptx.From); err != nil {
+		return nil, err
+	}
+	return ptx, nil
+}
+
+// sign validates and signs the transaction
+func sign(tx Transactioner, signer *signer.Signer, nonce uint64) (*SignedTransaction, error) {
+	if err := ValidateSender(tx, signer.PublicKey()); err != nil {
+		return nil, err
+	}
+	return construct(tx, signer.PublicKey(), nonce).Sign(signer)
+}
+
+// ---
+
+// Validate impl
+func (t *TransferAsset) Validate() error {
+	switch {
+	case !mint.ValidToken(uint16(t.Token)):
+		return invalid(t, "Token", ErrUnknown)
+	case t.Address == mint.PublicKey{}:
+		return invalid(t, "Address", ErrEmpty)
+	}
+	return positive(t, "Amount", t.Amount)
+}
+
+// Validate impl
+func (t *UserData) Validate() error {
+	switch {
+	case len(t.Data) == 0:
+		return invalid(t, "Data", ErrEmpty)
+	case uint64(len(t.Data)) > uint64(serializer.DefaultLimits.MaxFieldBytes):
+		return invalid(t, "Data", ErrTooLong)
+	}
+	return nil
+}
+
+// Validate impl
+func (t *RegisterNode) Validate() error {
+	switch {
+	case t.NodeAddress == mint.PublicKey{}:
+		return invalid(t, "NodeAddress", ErrEmpty)
+	case t.NodeIP == "":
+		return invalid(t, "NodeIP", ErrEmpty)
+	case len(t.NodeIP) > 64:
+		return invalid(t, "NodeIP", ErrTooLong)
+	case !validHost(t.NodeIP):
+		return invalid(t, "NodeIP", ErrMalformed)
+	}
+	return nil
+}
+
+// Validate impl
+func (t *UnregisterNode) Validate() error {
+	if t.NodeAddress == (mint.PublicKey{}) {
+		return invalid(t, "NodeAddress", ErrEmpty)
+	}
+	return nil
+}
+
+// Validate impl
+func (t *SetWalletTag) Validate() error {
+	return validateTag(t, t.Address, t.Tag)
+}
+
+// Validate impl
+func (t *UnsetWalletTag) Validate() error {
+	return validateTag(t, t.Address, t.Tag)
+}
+
+// Validate impl
+func (t *DistributionFee) Validate() error {
+	switch {
+	case t.OwnerAddress == mint.PublicKey{}:
+		return invalid(t, "OwnerAddress", ErrEmpty)
+	case t.AmountMNT == nil:
+		return invalid(t, "AmountMNT", ErrEmpty)
+	case t.AmountMNT.IsNeg():
+		return invalid(t, "AmountMNT", ErrNegative)
+	case t.AmountGOLD == nil:
+		return invalid(t, "AmountGOLD", ErrEmpty)
+	case t.AmountGOLD.IsNeg():
+		return invalid(t, "AmountGOLD", ErrNegative)
+	case t.AmountMNT.Value.Sign() == 0 && t.AmountGOLD.Value.Sign() == 0:
+		return invalid(t, "AmountMNT", ErrNotPositive)
+	}
+	return nil
+}
+
+// ---
+
+// invalid makes a validation error of the transaction field
+func invalid(tx Transactioner, field string, err error) error {
+	name := fmt.Sprintf("%T", tx)
+	name = name[strings.LastIndexByte(name, '.')+1:]
+	return &ValidationError{Type: name, Field: field, Err: err}
+}
+
+func positive(tx Transactioner, field string, a *amount.Amount) error {
+	switch {
+	case a == nil:
+		return invalid(tx, field, ErrEmpty)
+	case a.Value.Sign() <= 0:
+		return invalid(tx, field, ErrNotPositive)
+	}
+	return nil
+}
+
+func validateTag(tx Transactioner, address mint.PublicKey, tag mint.WalletTag) error {
+	switch {
+	case address == mint.PublicKey{}:
+		return invalid(tx, "Address", ErrEmpty)
+	case !mint.ValidWalletTag(uint8(tag)):
+		return invalid(tx, "Tag", ErrUnknown)
+	}
+	return nil
+}
+
+// validHost checks the value is an IP address, optionally with a port
+func validHost(s string) bool {
+	if net.ParseIP(s) != nil {
+		return true
+	}
+	host, port, err := net.SplitHostPort(s)
+	if err != nil || port == "" || net.ParseIP(host) == nil {
+		return false
+	}
+	n, err := strconv.ParseUint(port, 10, 16)
+	return err == nil && n != 0
+}
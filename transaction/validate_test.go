@@ -0,0 +1,95 @@
+package transaction
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	mint "github.com/void616/gm.mint"
+	"github.com/void616/gm.mint/amount"
+	"github.com/void616/gm.mint/signer"
+)
+
+func TestValidate(t *testing.T) {
+	s, _ := signer.New()
+	addr := mint.PublicKey{1}
+	one := amount.MustFromString("1")
+	zero := amount.New()
+	neg := amount.MustFromString("-1")
+
+	tests := []struct {
+		name  string
+		tx    Transactioner
+		field string
+		err   error
+	}{
+		{"transfer ok", &TransferAsset{Token: mint.TokenGOLD, Address: addr, Amount: one}, "", nil},
+		{"transfer token", &TransferAsset{Token: 42, Address: addr, Amount: one}, "Token", ErrUnknown},
+		{"transfer no address", &TransferAsset{Amount: one}, "Address", ErrEmpty},
+		{"transfer to sender", &TransferAsset{Address: s.PublicKey(), Amount: one}, "Address", ErrSender},
+		{"transfer nil amount", &TransferAsset{Address: addr}, "Amount", ErrEmpty},
+		{"transfer zero amount", &TransferAsset{Address: addr, Amount: zero}, "Amount", ErrNotPositive},
+		{"transfer negative amount", &TransferAsset{Address: addr, Amount: neg}, "Amount", ErrNotPositive},
+		{"user data ok", &UserData{Data: []byte{1}}, "", nil},
+		{"user data nil", &UserData{}, "Data", ErrEmpty},
+		{"user data empty", &UserData{Data: []byte{}}, "Data", ErrEmpty},
+		{"register ok", &RegisterNode{NodeAddress: addr, NodeIP: "10.0.0.1"}, "", nil},
+		{"register ok port", &RegisterNode{NodeAddress: addr, NodeIP: "[::1]:4010"}, "", nil},
+		{"register no address", &RegisterNode{NodeIP: "10.0.0.1"}, "NodeAddress", ErrEmpty},
+		{"register no ip", &RegisterNode{NodeAddress: addr}, "NodeIP", ErrEmpty},
+		{"register bad ip", &RegisterNode{NodeAddress: addr, NodeIP: "10.0.0"}, "NodeIP", ErrMalformed},
+		{"register bad port", &RegisterNode{NodeAddress: addr, NodeIP: "10.0.0.1:x"}, "NodeIP", ErrMalformed},
+		{"register named port", &RegisterNode{NodeAddress: addr, NodeIP: "10.0.0.1:http"}, "NodeIP", ErrMalformed},
+		{"register zero port", &RegisterNode{NodeAddress: addr, NodeIP: "10.0.0.1:0"}, "NodeIP", ErrMalformed},
+		{"register big port", &RegisterNode{NodeAddress: addr, NodeIP: "10.0.0.1:65536"}, "NodeIP", ErrMalformed},
+		{"register long ip", &RegisterNode{NodeAddress: addr, NodeIP: string(bytes.Repeat([]byte("1"), 65))}, "NodeIP", ErrTooLong},
+		{"unregister no address", &UnregisterNode{}, "NodeAddress", ErrEmpty},
+		{"set tag ok", &SetWalletTag{Address: s.PublicKey(), Tag: mint.WalletTagApproved}, "", nil},
+		{"set tag unknown", &SetWalletTag{Address: addr, Tag: 0}, "Tag", ErrUnknown},
+		{"unset tag no address", &UnsetWalletTag{Tag: mint.WalletTagApproved}, "Address", ErrEmpty},
+		{"fee ok", &DistributionFee{OwnerAddress: addr, AmountMNT: zero, AmountGOLD: one}, "", nil},
+		{"fee nil", &DistributionFee{OwnerAddress: addr, AmountMNT: one}, "AmountGOLD", ErrEmpty},
+		{"fee negative", &DistributionFee{OwnerAddress: addr, AmountMNT: neg, AmountGOLD: one}, "AmountMNT", ErrNegative},
+		{"fee zero", &DistributionFee{OwnerAddress: addr, AmountMNT: zero, AmountGOLD: zero}, "AmountMNT", ErrNotPositive},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := tt.tx.Sign(s, 1)
+			if tt.err == nil {
+				if err != nil {
+					t.Fatalf("Sign() error = %v", err)
+				}
+				return
+			}
+			var verr *ValidationError
+			if !errors.As(err, &verr) || !errors.Is(err, tt.err) || verr.Field != tt.field {
+				t.Fatalf("Sign() error = %v, want %v %v", err, tt.field, tt.err)
+			}
+		})
+	}
+}
+
+func TestParseValid(t *testing.T) {
+	s, _ := signer.New()
+
+	// invalid transaction is still parsed, but not validated
+	signed, err := construct(&TransferAsset{Address: s.PublicKey(), Amount: amount.New()}, s.PublicKey(), 1).Sign(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := (&TransferAsset{}).Parse(bytes.NewReader(signed.Data)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ParseValid(&TransferAsset{}, bytes.NewReader(signed.Data)); !errors.Is(err, ErrNotPositive) {
+		t.Fatalf("ParseValid() error = %v", err)
+	}
+
+	// sender is checked
+	signed, err = construct(&TransferAsset{Address: s.PublicKey(), Amount: amount.MustFromString("1")}, s.PublicKey(), 1).Sign(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ParseValid(&TransferAsset{}, bytes.NewReader(signed.Data)); !errors.Is(err, ErrSender) {
+		t.Fatalf("ParseValid() error = %v", err)
+	}
+}
@@ -0,0 +1,403 @@
+package wallet
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	mint "github.com/void616/gm.mint"
+	"github.com/void616/gm.mint/amount"
+	"github.com/void616/gm.mint/fee"
+	"github.com/void616/gm.mint/nonce"
+	"github.com/void616/gm.mint/policy"
+	"github.com/void616/gm.mint/signer"
+	"github.com/void616/gm.mint/transaction"
+)
+
+// Money is an amount of a token
+type Money struct {
+	Token  mint.Token
+	Amount *amount.Amount
+}
+
+// String representation, like: 1.500000000000000000 GOLD
+func (m Money) String() string {
+	return fmt.Sprintf("%v %v", m.Amount, m.Token)
+}
+
+// Signer signs transaction digests (see signer.Signer), it could be a hardware or a remote one
+type Signer interface {
+	PublicKey() mint.PublicKey
+	Sign(message []byte) mint.Signature
+}
+
+// Account is a state of an address in the chain
+type Account struct {
+	// Balances per token
+	Balances fee.Balances
+	// Nonce is the last nonce of the address included into the chain
+	Nonce uint64
+	// Tags of the address
+	Tags []mint.WalletTag
+}
+
+// Chain provides the state of addresses
+type Chain interface {
+	Account(ctx context.Context, addr mint.PublicKey) (*Account, error)
+}
+
+// Submitter sends a signed transaction to the network.
+// An error should implement Rejection if the transaction is definitely rejected
+type Submitter interface {
+	Submit(ctx context.Context, e *transaction.Envelope) error
+}
+
+// Rejection is implemented by Submitter errors telling the network has definitely rejected the transaction.
+// The nonce of such a transaction is released. Other errors (like a timeout) keep it allocated,
+// as the transaction could still be included (see UnknownOutcomeError)
+type Rejection interface {
+	Rejected() bool
+}
+
+// Tracker tells whether the network knows a submitted transaction
+type Tracker interface {
+	// Known returns false if the transaction is unknown to the network or rejected, so it won't be included
+	Known(ctx context.Context, id transaction.TxID) (bool, error)
+}
+
+// UnknownOutcomeError means the submission failed without a definite rejection (like on a timeout),
+// so the transaction could still be included. The receipt is returned along with the error.
+// The nonce stays allocated until the chain includes it, or the wallet's Tracker reports the transaction
+// is not known on the next sending (then the nonce is reused), or it's released by Wallet.Release
+type UnknownOutcomeError struct {
+	Receipt *Receipt
+	Err     error
+}
+
+// Error impl
+func (e *UnknownOutcomeError) Error() string {
+	return fmt.Sprintf("transaction %v with nonce %v: unknown outcome: %v", e.Receipt.ID, e.Receipt.Nonce, e.Err)
+}
+
+// Unwrap impl
+func (e *UnknownOutcomeError) Unwrap() error {
+	return e.Err
+}
+
+// RejectedError marks a Submitter error as a definite rejection
+type RejectedError struct {
+	Err error
+}
+
+// Error impl
+func (e *RejectedError) Error() string {
+	return e.Err.Error()
+}
+
+// Unwrap impl
+func (e *RejectedError) Unwrap() error {
+	return e.Err
+}
+
+// Rejected impl
+func (e *RejectedError) Rejected() bool {
+	return true
+}
+
+// Receipt of a submitted transaction
+type Receipt struct {
+	ID    transaction.TxID
+	Code  transaction.Code
+	Nonce uint64
+	From  mint.PublicKey
+	// To is a recipient, zero for user data
+	To mint.PublicKey
+	// Principal is a transferred amount, nil for user data
+	Principal *Money
+	// Fee estimated, zero amount for fee-free signers
+	Fee Money
+	// Envelope submitted
+	Envelope *transaction.Envelope
+}
+
+// InsufficientFundsError means the balance doesn't cover the principal and the fee
+type InsufficientFundsError struct {
+	Token     mint.Token
+	Required  *amount.Amount
+	Available *amount.Amount
+}
+
+// Error impl
+func (e *InsufficientFundsError) Error() string {
+	return fmt.Sprintf("insufficient %v funds: required %v, available %v", e.Token, e.Required, e.Available)
+}
+
+// Wallet sends transactions of the signer: checks the balance, picks the nonce, signs and submits.
+// Sending is serialized within the wallet. On UnknownOutcomeError the receipt is returned too
+type Wallet struct {
+	// Signer of the transactions
+	Signer Signer
+	// Chain provides the balances and the last nonce of the signer
+	Chain Chain
+	// Submitter sends the transactions
+	Submitter Submitter
+	// Nonces allocates the nonces, an in-memory manager is used if nil
+	Nonces *nonce.Manager
+	// Schedule of the fee, the default one if nil
+	Schedule *fee.Schedule
+	// Policy authorizes the transactions and exempts fee-free tags, optional
+	Policy *policy.Policy
+	// Tracker resolves the transactions of unknown outcome, the Submitter is used if it implements Tracker, optional
+	Tracker Tracker
+
+	mu sync.Mutex
+	// nonces of the transactions of unknown outcome
+	unknown map[uint64]transaction.TxID
+}
+
+// Address of the wallet
+func (w *Wallet) Address() mint.PublicKey {
+	return w.Signer.PublicKey()
+}
+
+// Transfer sends the money to the address
+func (w *Wallet) Transfer(ctx context.Context, to mint.PublicKey, m Money) (*Receipt, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	acc, err := w.account(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return w.send(ctx, acc, &transaction.TransferAsset{Token: m.Token, Address: to, Amount: m.Amount})
+}
+
+// SendData sends user data
+func (w *Wallet) SendData(ctx context.Context, data []byte) (*Receipt, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	acc, err := w.account(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return w.send(ctx, acc, &transaction.UserData{Data: data})
+}
+
+// Purge sends the whole balance (GOLD, then MNT) to the address, less the fee (see fee.PurgeGold, fee.PurgeMnt).
+// Tokens with insufficient balance are skipped, an error is returned if nothing is sent
+func (w *Wallet) Purge(ctx context.Context, to mint.PublicKey) ([]*Receipt, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	acc, err := w.account(ctx)
+	if err != nil {
+		return nil, err
+	}
+	gold, mnt := amount.FromAmount(acc.Balances[mint.TokenGOLD]), amount.FromAmount(acc.Balances[mint.TokenMNT])
+
+	schedule := w.schedule()
+	free := w.feeFree(acc)
+	var ret []*Receipt
+	for _, token := range []mint.Token{mint.TokenGOLD, mint.TokenMNT} {
+		var (
+			principal *amount.Amount
+			ok        bool
+		)
+		switch {
+		case free:
+			principal, ok = amount.FromAmount(acc.Balances[token]), acc.Balances[token].Value.Sign() > 0
+		case token == mint.TokenGOLD:
+			principal, _, ok = schedule.PurgeGold(gold, mnt)
+		default:
+			principal, _, ok = schedule.PurgeMnt(mnt)
+		}
+		if !ok {
+			continue
+		}
+		r, err := w.send(ctx, acc, &transaction.TransferAsset{Token: token, Address: to, Amount: principal})
+		if r != nil {
+			ret = append(ret, r)
+		}
+		if err != nil {
+			return ret, err
+		}
+	}
+	if len(ret) == 0 {
+		return nil, fmt.Errorf("nothing to purge: balances %v GOLD, %v MNT", gold, mnt)
+	}
+	return ret, nil
+}
+
+// Release the nonce of the transaction of unknown outcome (see UnknownOutcomeError), once it's known it won't be included
+func (w *Wallet) Release(r *Receipt) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if id, ok := w.unknown[r.Nonce]; !ok || id != r.ID {
+		return fmt.Errorf("transaction %v with nonce %v is not of unknown outcome", r.ID, r.Nonce)
+	}
+	delete(w.unknown, r.Nonce)
+	return w.nonces().Release(w.Address(), r.Nonce)
+}
+
+// ---
+
+func (w *Wallet) schedule() *fee.Schedule {
+	if w.Schedule != nil {
+		return w.Schedule
+	}
+	return fee.Default()
+}
+
+func (w *Wallet) account(ctx context.Context) (*Account, error) {
+	acc, err := w.Chain.Account(ctx, w.Address())
+	if err != nil {
+		return nil, err
+	}
+	if acc.Balances == nil {
+		acc.Balances = fee.Balances{}
+	}
+	for _, token := range []mint.Token{mint.TokenGOLD, mint.TokenMNT} {
+		if acc.Balances[token] == nil {
+			acc.Balances[token] = amount.New()
+		}
+	}
+	return acc, nil
+}
+
+func (w *Wallet) feeFree(acc *Account) bool {
+	return w.Policy != nil && w.Policy.IsFeeFree(acc.Tags)
+}
+
+// send checks the balance, signs and submits the transaction
+func (w *Wallet) send(ctx context.Context, acc *Account, tx transaction.Transactioner) (*Receipt, error) {
+	from := w.Address()
+	if err := transaction.ValidateSender(tx, from); err != nil {
+		return nil, err
+	}
+	if w.Policy != nil {
+		if err := w.Policy.Authorize(tx, acc.Tags); err != nil {
+			return nil, err
+		}
+	}
+
+	// fee and balance
+	est, err := w.schedule().EstimateSender(tx, acc.Balances, w.feeFree(acc))
+	if err != nil {
+		return nil, err
+	}
+	r := &Receipt{Code: tx.Code(), From: from}
+	required := make(map[mint.Token]*amount.Amount)
+	if t, ok := tx.(*transaction.TransferAsset); ok {
+		r.To = t.Address
+		r.Principal = &Money{Token: t.Token, Amount: amount.FromAmount(t.Amount)}
+		required[t.Token] = amount.FromAmount(t.Amount)
+	}
+	for _, item := range est.Breakdown {
+		r.Fee = Money{Token: item.Token, Amount: amount.FromAmount(item.Fee)}
+		if v, ok := required[item.Token]; ok {
+			v.Value.Add(v.Value, r.Fee.Amount.Value)
+		} else {
+			required[item.Token] = amount.FromAmount(r.Fee.Amount)
+		}
+	}
+	for token, req := range required {
+		avail := acc.Balances[token]
+		if avail.Value.Cmp(req.Value) < 0 {
+			return nil, &InsufficientFundsError{Token: token, Required: req, Available: amount.FromAmount(avail)}
+		}
+	}
+
+	// nonce
+	nonces := w.nonces()
+	if err := nonces.Sync(from, acc.Nonce); err != nil {
+		return nil, err
+	}
+	if err := w.resolve(ctx, acc.Nonce); err != nil {
+		return nil, err
+	}
+	n, err := nonces.Next(from)
+	if err != nil {
+		return nil, err
+	}
+	release := func(err error) error {
+		if rerr := nonces.Release(from, n); rerr != nil {
+			return fmt.Errorf("%v; release nonce: %v", err, rerr)
+		}
+		return err
+	}
+
+	// sign
+	unsigned, err := transaction.Unsigned(tx, from, n)
+	if err != nil {
+		return nil, release(err)
+	}
+	signature := w.Signer.Sign(unsigned.Digest[:])
+	if err := signer.Verify(from, unsigned.Digest[:], signature); err != nil {
+		return nil, release(fmt.Errorf("signer made invalid signature: %v", err))
+	}
+	signed := unsigned.Signed(signature)
+
+	// submit
+	e := &transaction.Envelope{Code: tx.Code(), Data: signed.Data}
+	if err := w.Submitter.Submit(ctx, e); err != nil {
+		var rej Rejection
+		if errors.As(err, &rej) && rej.Rejected() {
+			return nil, release(err)
+		}
+		if w.unknown == nil {
+			w.unknown = make(map[uint64]transaction.TxID)
+		}
+		w.unknown[n] = signed.ID()
+		r.ID, r.Nonce, r.Envelope = signed.ID(), n, e
+		return r, &UnknownOutcomeError{Receipt: r, Err: err}
+	}
+
+	// spend the balance for the following transactions of the same call (like Purge)
+	for token, req := range required {
+		left := amount.FromAmount(acc.Balances[token])
+		left.Value.Sub(left.Value, req.Value)
+		acc.Balances[token] = left
+	}
+
+	r.ID, r.Nonce, r.Envelope = signed.ID(), n, e
+	return r, nil
+}
+
+// resolve forgets the transactions of unknown outcome up to the last included nonce,
+// and releases the nonces of the ones the tracker doesn't know
+func (w *Wallet) resolve(ctx context.Context, last uint64) error {
+	tracker := w.Tracker
+	if tracker == nil {
+		tracker, _ = w.Submitter.(Tracker)
+	}
+	for n, id := range w.unknown {
+		if n <= last {
+			delete(w.unknown, n)
+			continue
+		}
+		if tracker == nil {
+			continue
+		}
+		known, err := tracker.Known(ctx, id)
+		if err != nil {
+			return err
+		}
+		if !known {
+			if err := w.nonces().Release(w.Address(), n); err != nil {
+				return err
+			}
+			delete(w.unknown, n)
+		}
+	}
+	return nil
+}
+
+func (w *Wallet) nonces() *nonce.Manager {
+	if w.Nonces == nil {
+		w.Nonces = nonce.New(nonce.NewMemoryStore())
+	}
+	return w.Nonces
+}
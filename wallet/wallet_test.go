@@ -0,0 +1,230 @@
+package wallet
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"testing"
+
+	mint "github.com/void616/gm.mint"
+	"github.com/void616/gm.mint/amount"
+	"github.com/void616/gm.mint/fee"
+	"github.com/void616/gm.mint/policy"
+	"github.com/void616/gm.mint/signer"
+	"github.com/void616/gm.mint/transaction"
+)
+
+type testChain struct {
+	acc Account
+}
+
+func (c *testChain) Account(ctx context.Context, addr mint.PublicKey) (*Account, error) {
+	acc := c.acc
+	acc.Balances = fee.Balances{}
+	for k, v := range c.acc.Balances {
+		acc.Balances[k] = amount.FromAmount(v)
+	}
+	return &acc, nil
+}
+
+type testSubmitter struct {
+	sent []*transaction.Envelope
+	err  error
+}
+
+func (s *testSubmitter) Submit(ctx context.Context, e *transaction.Envelope) error {
+	if s.err != nil {
+		return s.err
+	}
+	s.sent = append(s.sent, e)
+	return nil
+}
+
+func testWallet(gold, mnt string) (*Wallet, *testChain, *testSubmitter) {
+	s, _ := signer.New()
+	chain := &testChain{acc: Account{
+		Balances: fee.Balances{mint.TokenGOLD: amount.MustFromString(gold), mint.TokenMNT: amount.MustFromString(mnt)},
+		Nonce:    5,
+	}}
+	sub := &testSubmitter{}
+	return &Wallet{Signer: s, Chain: chain, Submitter: sub}, chain, sub
+}
+
+func TestWallet_Transfer(t *testing.T) {
+	w, _, sub := testWallet("1", "10")
+	to := mint.PublicKey{1}
+
+	r, err := w.Transfer(context.Background(), to, Money{Token: mint.TokenGOLD, Amount: amount.MustFromString("0.5")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantFee := fee.GoldFee(amount.MustFromString("0.5"), amount.MustFromString("10"))
+	if r.Nonce != 6 || r.To != to || r.Fee.Token != mint.TokenGOLD || r.Fee.Amount.Value.Cmp(wantFee.Value) != 0 || len(sub.sent) != 1 {
+		t.Fatalf("Transfer() = %+v", r)
+	}
+
+	// the submitted envelope is signed by the wallet
+	tx, ptx, err := sub.sent[0].Open()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ptx.From != w.Address() || ptx.Nonce != 6 || !ptx.Signed || ptx.ID() != r.ID || tx.(*transaction.TransferAsset).Address != to {
+		t.Fatalf("submitted %+v", ptx)
+	}
+	if err := signer.Verify(ptx.From, ptx.Digest[:], ptx.Signature); err != nil {
+		t.Fatal(err)
+	}
+
+	// the chain is not updated yet, the next nonce is picked anyway
+	r, err = w.SendData(context.Background(), []byte("hello"))
+	if err != nil || r.Nonce != 7 || r.Principal != nil {
+		t.Fatalf("SendData() = %+v, %v", r, err)
+	}
+}
+
+func TestWallet_InsufficientFunds(t *testing.T) {
+	w, _, sub := testWallet("1", "10")
+
+	_, err := w.Transfer(context.Background(), mint.PublicKey{1}, Money{Token: mint.TokenGOLD, Amount: amount.MustFromString("1")})
+	var ierr *InsufficientFundsError
+	if !errors.As(err, &ierr) || ierr.Token != mint.TokenGOLD || len(sub.sent) != 0 {
+		t.Fatalf("Transfer() error = %v", err)
+	}
+
+	// a rejected submission releases the nonce
+	sub.err = &RejectedError{Err: errors.New("bad nonce")}
+	if _, err := w.SendData(context.Background(), []byte{1}); err == nil {
+		t.Fatal("SendData() is not failed")
+	}
+	sub.err = nil
+	if r, err := w.SendData(context.Background(), []byte{1}); err != nil || r.Nonce != 6 {
+		t.Fatalf("SendData() = %+v, %v", r, err)
+	}
+
+	// an unknown outcome keeps the nonce pending, the transaction could be included
+	sub.err = errors.New("timeout")
+	r, err := w.SendData(context.Background(), []byte{1})
+	var uerr *UnknownOutcomeError
+	if !errors.As(err, &uerr) || r == nil || uerr.Receipt != r || r.Nonce != 7 {
+		t.Fatalf("SendData() = %+v, %v", r, err)
+	}
+	if id, _ := r.Envelope.ID(); r.ID != id {
+		t.Fatalf("receipt ID %v, envelope ID %v", r.ID, id)
+	}
+	sub.err = nil
+	if r, err := w.SendData(context.Background(), []byte{1}); err != nil || r.Nonce != 8 {
+		t.Fatalf("SendData() = %+v, %v", r, err)
+	}
+
+	// released by the caller
+	if err := w.Release(r); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Release(r); err == nil {
+		t.Fatal("Release() twice is not failed")
+	}
+	if r, err := w.SendData(context.Background(), []byte{1}); err != nil || r.Nonce != 7 {
+		t.Fatalf("SendData() = %+v, %v", r, err)
+	}
+}
+
+type testTracker map[transaction.TxID]bool
+
+func (t testTracker) Known(ctx context.Context, id transaction.TxID) (bool, error) {
+	return t[id], nil
+}
+
+func TestWallet_Tracker(t *testing.T) {
+	w, chain, sub := testWallet("1", "10")
+	tracker := testTracker{}
+	w.Tracker = tracker
+
+	sub.err = errors.New("timeout")
+	lost, err := w.SendData(context.Background(), []byte{1})
+	if lost == nil || err == nil {
+		t.Fatalf("SendData() = %+v, %v", lost, err)
+	}
+	tracker[lost.ID] = true
+	delivered, err := w.SendData(context.Background(), []byte{2})
+	if delivered == nil || err == nil || delivered.Nonce != 7 {
+		t.Fatalf("SendData() = %+v, %v", delivered, err)
+	}
+	tracker[delivered.ID] = true
+	delete(tracker, lost.ID)
+
+	// the lost nonce is reused, the delivered one is kept
+	sub.err = nil
+	if r, err := w.SendData(context.Background(), []byte{3}); err != nil || r.Nonce != 6 {
+		t.Fatalf("SendData() = %+v, %v", r, err)
+	}
+	if r, err := w.SendData(context.Background(), []byte{4}); err != nil || r.Nonce != 8 {
+		t.Fatalf("SendData() = %+v, %v", r, err)
+	}
+
+	// forgotten once included
+	chain.acc.Nonce = 8
+	if r, err := w.SendData(context.Background(), []byte{5}); err != nil || r.Nonce != 9 || len(w.unknown) != 0 {
+		t.Fatalf("SendData() = %+v, %v, unknown %v", r, err, w.unknown)
+	}
+}
+
+func TestWallet_Purge(t *testing.T) {
+	w, _, sub := testWallet("1.23451851925925", "1000")
+	to := mint.PublicKey{1}
+
+	rs, err := w.Purge(context.Background(), to)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rs) != 2 || len(sub.sent) != 2 || rs[0].Nonce != 6 || rs[1].Nonce != 7 {
+		t.Fatalf("Purge() = %+v", rs)
+	}
+	for i, token := range []mint.Token{mint.TokenGOLD, mint.TokenMNT} {
+		r := rs[i]
+		if r.Principal.Token != token || r.Fee.Token != token {
+			t.Fatalf("receipt %v: %+v", i, r)
+		}
+	}
+	if p, _, _ := fee.PurgeGold(amount.MustFromString("1.23451851925925"), amount.MustFromString("1000")); rs[0].Principal.Amount.Value.Cmp(p.Value) != 0 {
+		t.Fatalf("GOLD principal %v, want %v", rs[0].Principal.Amount, p)
+	}
+	if p, _, _ := fee.PurgeMnt(amount.MustFromString("1000")); rs[1].Principal.Amount.Value.Cmp(p.Value) != 0 {
+		t.Fatalf("MNT principal %v, want %v", rs[1].Principal.Amount, p)
+	}
+
+	// PurgeGold fee is 1 wei less than the fee of its principal
+	w, _, sub = testWallet("0.634650529656337694", "0")
+	rs, err = w.Purge(context.Background(), to)
+	if err != nil || len(rs) != 1 {
+		t.Fatalf("Purge() = %+v, %v", rs, err)
+	}
+	spent := new(big.Int).Add(rs[0].Principal.Amount.Value, rs[0].Fee.Amount.Value)
+	if want := amount.MustFromString("0.634650529656337694"); spent.Cmp(want.Value) > 0 || rs[0].Principal.Amount.String() != amount.MustFromString("0.634016513143194499").String() {
+		t.Fatalf("Purge() principal %v, fee %v", rs[0].Principal.Amount, rs[0].Fee.Amount)
+	}
+
+	// nothing left
+	w, _, _ = testWallet("0", "0.01")
+	if _, err := w.Purge(context.Background(), to); err == nil {
+		t.Fatal("Purge() is not failed")
+	}
+}
+
+func TestWallet_Policy(t *testing.T) {
+	w, chain, _ := testWallet("0", "1")
+	w.Policy = policy.Default()
+
+	// GOLD requires "approved"
+	_, err := w.Transfer(context.Background(), mint.PublicKey{1}, Money{Token: mint.TokenGOLD, Amount: amount.MustFromString("1")})
+	var derr *policy.DeniedError
+	if !errors.As(err, &derr) {
+		t.Fatalf("Transfer() error = %v", err)
+	}
+
+	// fee-free signer sends the whole balance
+	chain.acc.Tags = []mint.WalletTag{mint.WalletTagNoFee}
+	rs, err := w.Purge(context.Background(), mint.PublicKey{1})
+	if err != nil || len(rs) != 1 || rs[0].Principal.Amount.Value.Cmp(amount.MustFromString("1").Value) != 0 || rs[0].Fee.Amount.Value.Sign() != 0 {
+		t.Fatalf("Purge() = %+v, %v", rs, err)
+	}
+}